@@ -1,16 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	log2 "log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/NYTimes/logrotate"
@@ -27,9 +32,12 @@ import (
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
 	"github.com/pterodactyl/wings/loggers/cli"
+	"github.com/pterodactyl/wings/metrics"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/router"
+	"github.com/pterodactyl/wings/router/tokens"
 	"github.com/pterodactyl/wings/server"
+	"github.com/pterodactyl/wings/server/installcache"
 	"github.com/pterodactyl/wings/sftp"
 	"github.com/pterodactyl/wings/system"
 )
@@ -82,6 +90,7 @@ func init() {
 	rootCommand.AddCommand(versionCommand)
 	rootCommand.AddCommand(configureCmd)
 	rootCommand.AddCommand(newDiagnosticsCommand())
+	rootCommand.AddCommand(newRotateTokenCommand())
 }
 
 func rootCmdRun(cmd *cobra.Command, _ []string) {
@@ -137,12 +146,18 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 		return
 	}
 
+	if err := tokens.OpenTokenStore(cmd.Context(), config.Get().System.GetTokenStorePath()); err != nil {
+		log.WithField("error", err).Error("failed to open token store, used tokens will not be remembered across restarts")
+	}
+
 	pclient := remote.New(
 		config.Get().PanelLocation,
 		remote.WithCredentials(config.Get().AuthenticationTokenId, config.Get().AuthenticationToken),
 		remote.WithHttpClient(&http.Client{
 			Timeout: time.Second * time.Duration(config.Get().RemoteQuery.Timeout),
 		}),
+		remote.WithOutbox(config.Get().System.GetOutboxPath()),
+		remote.WithSigningKey(config.Get().RemoteQuery.SigningKey),
 	)
 
 	manager, err := server.NewManager(cmd.Context(), pclient)
@@ -243,6 +258,14 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 				// make a call to set that state just to ensure we don't ever accidentally end up with some invalid
 				// state being tracked.
 				s.Environment.SetState(environment.ProcessOfflineState)
+
+				// Servers that were hibernating need to be listening for a connection again
+				// so that they are started when a client tries to connect.
+				if st == server.HibernatingState {
+					if err := s.Hibernator().Resume(); err != nil {
+						s.Log().WithField("error", err).Warn("failed to resume hibernation for server")
+					}
+				}
 			}
 		})
 	}
@@ -265,6 +288,29 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 		}
 	}()
 
+	if config.Get().Api.Metrics.Enabled {
+		metrics.MustRegister(manager.Collector())
+		go func() {
+			if err := metrics.Serve(); err != nil {
+				log.WithField("error", err).Error("failed to serve metrics webserver")
+			}
+		}()
+	}
+
+	if config.Get().Docker.InstallerCache.Enabled {
+		go func() {
+			if err := installcache.Serve(cmd.Context()); err != nil {
+				log.WithField("error", err).Error("failed to serve installer download cache")
+			}
+		}()
+	}
+
+	// Refresh the images used by servers during off-peak hours so that servers do
+	// not need to wait for them to be pulled when they boot.
+	manager.StartImagePrePuller(cmd.Context())
+
+	go reloadOnSignal(cmd.Context(), manager)
+
 	go func() {
 		log.Info("updating server states on Panel: marking installing/restoring servers as normal")
 		// Update all the servers on the Panel to be in a valid state if they're
@@ -274,6 +320,10 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 		}
 	}()
 
+	// Deliver any status callbacks that were queued while the Panel could not be
+	// reached, either during this run or before Wings was last stopped.
+	pclient.StartOutbox(cmd.Context())
+
 	sys := config.Get().System
 	// Ensure the archive directory exists.
 	if err := os.MkdirAll(sys.ArchiveDirectory, 0755); err != nil {
@@ -307,6 +357,22 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 		TLSConfig: config.DefaultTLSConfig,
 	}
 
+	// Client certificates are verified if they are given, requiring them is left
+	// to the authorization middleware since browsers also connect to this server.
+	if api.Ssl.ClientCA != "" {
+		if !api.Ssl.Enabled && !autotls {
+			log.Fatal("a client CA cannot be used without enabling SSL for the webserver")
+		}
+		pool, err := loadCertPool(api.Ssl.ClientCA)
+		if err != nil {
+			log.WithField("error", err).Fatal("failed to load client CA for webserver")
+		}
+		s.TLSConfig = s.TLSConfig.Clone()
+		s.TLSConfig.ClientCAs = pool
+		s.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		log.WithField("client_ca", api.Ssl.ClientCA).Info("requiring client certificates for requests authenticated with the node token")
+	}
+
 	// Check if the server should run with TLS but using autocert.
 	if autotls {
 		m := autocert.Manager{
@@ -348,6 +414,20 @@ func rootCmdRun(cmd *cobra.Command, _ []string) {
 	}
 }
 
+// loadCertPool returns a certificate pool containing the PEM encoded certificates
+// in the file at the given path.
+func loadCertPool(p string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("cmd/root: no certificates found in %s", p)
+	}
+	return pool, nil
+}
+
 // Reads the configuration from the disk and then sets up the global singleton
 // with all the configuration values.
 func initConfig() {
@@ -390,6 +470,32 @@ func initLogging() {
 	log.WithField("path", p).Info("writing log files to disk")
 }
 
+// reloadOnSignal reloads the configuration file every time SIGHUP is received.
+// If the file cannot be parsed or is invalid the running configuration is kept.
+func reloadOnSignal(ctx context.Context, manager *server.Manager) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			log.Info("received SIGHUP, reloading configuration file")
+			res, err := config.Reload()
+			if err != nil {
+				log.WithField("error", err).Error("failed to reload configuration, keeping the running configuration")
+				continue
+			}
+			manager.ApplyConfiguration()
+			log.WithField("fields", res.Applied).Info("applied configuration changes")
+			if len(res.RequiresRestart) > 0 {
+				log.WithField("fields", res.RequiresRestart).Warn("some configuration changes will not take effect until wings is restarted")
+			}
+		}
+	}
+}
+
 // Prints the wings logo, nothing special here!
 func printLogo() {
 	fmt.Printf(colorstring.Color(`
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/loggers/cli"
+)
+
+var rotateTokenArgs struct {
+	TokenId     string
+	Token       string
+	GracePeriod time.Duration
+	Insecure    bool
+	ClientCert  string
+	ClientKey   string
+}
+
+func newRotateTokenCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "rotate-token",
+		Short: "Replace the node token, keeping the current token valid for a grace period.",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			initConfig()
+			log.SetHandler(cli.Default)
+		},
+		Run: rotateTokenCmdRun,
+	}
+
+	command.Flags().StringVar(&rotateTokenArgs.TokenId, "token-id", "", "the new token ID, the current ID is kept if not provided")
+	command.Flags().StringVar(&rotateTokenArgs.Token, "token", "", "the new token for the node")
+	command.Flags().DurationVar(&rotateTokenArgs.GracePeriod, "grace-period", time.Minute*5, "how long the current token remains valid for")
+	command.Flags().BoolVar(&rotateTokenArgs.Insecure, "allow-insecure", false, "disable certificate checking when connecting to the running instance")
+	command.Flags().StringVar(&rotateTokenArgs.ClientCert, "client-cert", "", "the client certificate to present to the running instance when api.ssl.client_ca is set")
+	command.Flags().StringVar(&rotateTokenArgs.ClientKey, "client-key", "", "the private key for the client certificate")
+	_ = command.MarkFlagRequired("token")
+
+	return command
+}
+
+// rotateTokenCmdRun sends the new token to the running Wings instance so that it
+// is swapped without a restart. If Wings is not running the configuration file
+// is updated directly instead.
+func rotateTokenCmdRun(cmd *cobra.Command, _ []string) {
+	err := rotateRunningToken()
+	if err == nil {
+		fmt.Println("The node token has been rotated on the running instance.")
+		return
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		fmt.Printf("Failed to rotate the node token: %s\n", err)
+		os.Exit(1)
+	}
+
+	log.WithField("error", err).Warn("could not connect to the running instance, updating the configuration file instead")
+	if err := config.RotateToken(rotateTokenArgs.TokenId, rotateTokenArgs.Token, rotateTokenArgs.GracePeriod); err != nil {
+		fmt.Printf("Failed to rotate the node token: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("The node token has been rotated in the configuration file.")
+}
+
+func rotateRunningToken() error {
+	if rotateTokenArgs.GracePeriod < 0 || rotateTokenArgs.GracePeriod > config.MaxTokenGracePeriod {
+		return fmt.Errorf("the grace period must be between 0 and %s", config.MaxTokenGracePeriod)
+	}
+	api := config.Get().Api
+	host := api.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	scheme := "http"
+	if api.Ssl.Enabled {
+		scheme = "https"
+	}
+
+	b, err := json.Marshal(map[string]interface{}{
+		"token_id":     rotateTokenArgs.TokenId,
+		"token":        rotateTokenArgs.Token,
+		"grace_period": int(rotateTokenArgs.GracePeriod.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(api.Port))+"/api/system/rotate-token", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+config.Get().AuthenticationToken)
+
+	tc := &tls.Config{InsecureSkipVerify: rotateTokenArgs.Insecure}
+	if rotateTokenArgs.ClientCert != "" || rotateTokenArgs.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(rotateTokenArgs.ClientCert, rotateTokenArgs.ClientKey)
+		if err != nil {
+			return fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	} else if api.Ssl.ClientCA != "" {
+		return errors.New("a client certificate signed by the configured client CA is required, use --client-cert and --client-key")
+	}
+	c := &http.Client{Timeout: time.Second * 15, Transport: &http.Transport{TLSClientConfig: tc}}
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("unexpected response from the running instance (HTTP/%d): %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -51,6 +51,7 @@ var DefaultTLSConfig = &tls.Config{
 var mu sync.RWMutex
 var _config *Configuration
 var _jwtAlgo *jwt.HMACSHA
+var _previousJwtAlgo *jwt.HMACSHA
 var _debugViaFlag bool
 
 // Locker specific to writing the configuration to the disk, this happens
@@ -81,6 +82,12 @@ type ApiConfiguration struct {
 		Enabled         bool   `json:"enabled" yaml:"enabled"`
 		CertificateFile string `json:"cert" yaml:"cert"`
 		KeyFile         string `json:"key" yaml:"key"`
+
+		// The path to a CA certificate that the Panel's client certificate must be
+		// signed by. When set every request authenticated with the node token must
+		// also present a valid client certificate. Requests from users that are
+		// authenticated with a signed JWT do not need one.
+		ClientCA string `json:"client_ca" yaml:"client_ca"`
 	}
 
 	// Determines if functionality for allowing remote download of files into server directories
@@ -90,6 +97,24 @@ type ApiConfiguration struct {
 
 	// The maximum size for files uploaded through the Panel in bytes.
 	UploadLimit int `default:"100" json:"upload_limit" yaml:"upload_limit"`
+
+	Metrics MetricsConfiguration `json:"metrics" yaml:"metrics"`
+}
+
+// MetricsConfiguration defines the settings for the Prometheus metrics endpoint.
+// The endpoint is served by its own webserver so that it can be bound to an
+// internal interface separately from the API.
+type MetricsConfiguration struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// The interface and port that the metrics webserver should bind to.
+	Host string `default:"127.0.0.1" json:"host" yaml:"host"`
+	Port int    `default:"9101" json:"port" yaml:"port"`
+
+	// The bearer token that must be provided to access the metrics. If left empty
+	// the endpoint is not authenticated, so it should only be bound to an interface
+	// that is not publicly accessible.
+	Token string `json:"token" yaml:"token"`
 }
 
 // RemoteQueryConfiguration defines the configuration settings for remote requests
@@ -112,6 +137,15 @@ type RemoteQueryConfiguration struct {
 	// 50 servers is likely just as quick as two for 100 or one for 400, and will certainly
 	// be less likely to cause performance issues on the Panel.
 	BootServersPerPage int `default:"50" yaml:"boot_servers_per_page"`
+
+	// If enabled the configuration for each server is saved to the disk whenever it
+	// is fetched from the Panel, and used to boot and start the servers if the Panel
+	// cannot be reached. The servers are synced with the Panel once it is back.
+	CacheConfigurations bool `default:"true" yaml:"cache_configurations"`
+
+	// The key used to sign every request made to the Panel with a HMAC signature
+	// including a timestamp and nonce. Requests are not signed if this is empty.
+	SigningKey string `yaml:"signing_key"`
 }
 
 // SystemConfiguration defines basic system configuration settings.
@@ -148,6 +182,19 @@ type SystemConfiguration struct {
 		Gid int
 	}
 
+	// When enabled a snapshot of the server files is taken before a reinstall is
+	// started. If the installation script fails the files are restored from it so
+	// that a failed reinstall does not leave the server in a partially wiped state.
+	// The backup directory must have enough free space for all of the server files,
+	// otherwise the reinstall is refused. Only when this is enabled is a non-zero
+	// exit code from the installation script treated as a failed installation.
+	SnapshotBeforeReinstall bool `default:"false" yaml:"snapshot_before_reinstall"`
+
+	// UserIsolation runs each server as its own user rather than the shared
+	// Pterodactyl user, so that a process escaping from a container is not able to
+	// access the files of any other server on the node.
+	UserIsolation UserIsolationConfiguration `yaml:"user_isolation"`
+
 	// The amount of time in seconds that can elapse before a server's disk space calculation is
 	// considered stale and a re-check should occur. DANGER: setting this value too low can seriously
 	// impact system performance and cause massive I/O bottlenecks and high CPU usage for the Wings
@@ -171,6 +218,15 @@ type SystemConfiguration struct {
 	// The number of lines to send when a server connects to the websocket.
 	WebsocketLogCount int `default:"150" yaml:"websocket_log_count"`
 
+	ConsoleLogs ConsoleLogs `yaml:"console_logs"`
+
+	ConsoleTriggers ConsoleTriggers `yaml:"console_triggers"`
+
+	// The number of seconds between each query sent to a running server to determine
+	// the number of players connected to it. This only applies to servers that have
+	// a query protocol configured.
+	QueryInterval int `default:"15" yaml:"query_interval"`
+
 	Sftp SftpConfiguration `yaml:"sftp"`
 
 	CrashDetection CrashDetection `yaml:"crash_detection"`
@@ -192,6 +248,56 @@ type CrashDetection struct {
 	Timeout int `default:"60" json:"timeout"`
 }
 
+type ConsoleLogs struct {
+	// Determines if the console output of each server should be persisted to the disk
+	// in the log directory. This allows older output to be searched after the container
+	// itself has been removed or the Docker log has been truncated.
+	Enabled bool `default:"true" yaml:"enabled"`
+
+	// The size in megabytes the active console log file for a server can reach before
+	// it is compressed and a new file is started.
+	MaxSize int64 `default:"10" yaml:"max_size"`
+
+	// The number of compressed console log files to keep for each server. Once this
+	// is exceeded the oldest files are removed.
+	MaxFiles int `default:"5" yaml:"max_files"`
+}
+
+type UserIsolationConfiguration struct {
+	// Determines if each server is assigned its own user and group ID from the range
+	// below. IDs that belong to a user or group on the host are never assigned.
+	Enabled bool `default:"false" yaml:"enabled"`
+
+	// The first ID that can be assigned to a server. When RemapUser is set this is
+	// an ID inside of the user namespace of the containers, otherwise it is used
+	// both inside of the container and for the files on the host.
+	Start int `default:"200000" yaml:"start"`
+
+	// The number of IDs in the range, which limits the number of servers that can
+	// exist on the node while isolation is enabled.
+	Size int `default:"65536" yaml:"size"`
+
+	// The user Docker has been configured to remap container user namespaces to
+	// using the "userns-remap" daemon option, such as "dockremap". When this is set
+	// the files for each server are owned on the host by the ID its container user
+	// maps to in the subordinate ranges for this user in /etc/subuid and
+	// /etc/subgid, so that root inside of a container is not root on the host. The
+	// range above must fit within those subordinate ranges.
+	RemapUser string `yaml:"remap_user"`
+}
+
+type ConsoleTriggers struct {
+	// A list of additional hosts that console triggers are allowed to send webhooks
+	// to. Webhooks may always be sent to loopback addresses, any other host must be
+	// explicitly listed here to prevent servers from making arbitrary requests from
+	// the node.
+	WebhookAllowlist []string `yaml:"webhook_allowlist"`
+
+	// The number of seconds to wait for a webhook request to complete before it is
+	// canceled.
+	WebhookTimeout int `default:"5" yaml:"webhook_timeout"`
+}
+
 type Backups struct {
 	// WriteLimit imposes a Disk I/O write limit on backups to the disk, this affects all
 	// backup drivers as the archiver must first write the file to the disk in order to
@@ -261,6 +367,12 @@ type Configuration struct {
 	// validate against it.
 	AuthenticationToken string `json:"token" yaml:"token"`
 
+	// The token that was replaced the last time the token was rotated. It remains
+	// valid until PreviousTokenExpiresAt so that requests from the Panel, and JWTs
+	// it has already issued, are not rejected while it switches to the new token.
+	PreviousAuthenticationToken string    `json:"-" yaml:"previous_token,omitempty"`
+	PreviousTokenExpiresAt      time.Time `json:"-" yaml:"previous_token_expires_at,omitempty"`
+
 	Api    ApiConfiguration    `json:"api" yaml:"api"`
 	System SystemConfiguration `json:"system" yaml:"system"`
 	Docker DockerConfiguration `json:"docker" yaml:"docker"`
@@ -307,6 +419,12 @@ func Set(c *Configuration) {
 	if _config == nil || _config.AuthenticationToken != c.AuthenticationToken {
 		_jwtAlgo = jwt.NewHS256([]byte(c.AuthenticationToken))
 	}
+	if _config == nil || _config.PreviousAuthenticationToken != c.PreviousAuthenticationToken {
+		_previousJwtAlgo = nil
+		if c.PreviousAuthenticationToken != "" {
+			_previousJwtAlgo = jwt.NewHS256([]byte(c.PreviousAuthenticationToken))
+		}
+	}
 	_config = c
 	mu.Unlock()
 }
@@ -354,6 +472,61 @@ func GetJwtAlgorithm() *jwt.HMACSHA {
 	return _jwtAlgo
 }
 
+// GetPreviousJwtAlgorithm returns the JWT algorithm for the token that was
+// replaced the last time the token was rotated, or nil if there is not one or
+// the grace period for it has ended.
+func GetPreviousJwtAlgorithm() *jwt.HMACSHA {
+	mu.RLock()
+	defer mu.RUnlock()
+	if _previousJwtAlgo == nil || !time.Now().Before(_config.PreviousTokenExpiresAt) {
+		return nil
+	}
+	return _previousJwtAlgo
+}
+
+// AuthenticationTokens returns the tokens that requests to this instance can
+// currently be authenticated with, which includes the previous token while it
+// is still within the grace period after a rotation.
+func AuthenticationTokens() []string {
+	c := Get()
+	tokens := []string{c.AuthenticationToken}
+	if c.PreviousAuthenticationToken != "" && time.Now().Before(c.PreviousTokenExpiresAt) {
+		tokens = append(tokens, c.PreviousAuthenticationToken)
+	}
+	return tokens
+}
+
+// MaxTokenGracePeriod is the longest time the previous token remains valid for
+// after the authentication token is rotated.
+const MaxTokenGracePeriod = time.Hour * 24
+
+// RotateToken replaces the authentication token for this instance and writes
+// the change to the disk. The current token remains valid for the grace period
+// given. If the id is empty the current token ID is kept.
+func RotateToken(id string, token string, grace time.Duration) error {
+	if token == "" {
+		return errors.New("config: cannot rotate to an empty token")
+	}
+	if grace < 0 || grace > MaxTokenGracePeriod {
+		return errors.New("config: grace period must be between 0 and " + MaxTokenGracePeriod.String())
+	}
+	c := Get()
+	if token == c.AuthenticationToken {
+		return errors.New("config: new token must be different from the current token")
+	}
+	if id != "" {
+		c.AuthenticationTokenId = id
+	}
+	c.PreviousAuthenticationToken = c.AuthenticationToken
+	c.PreviousTokenExpiresAt = time.Now().Add(grace)
+	c.AuthenticationToken = token
+	if err := WriteToDisk(c); err != nil {
+		return err
+	}
+	Set(c)
+	return nil
+}
+
 // WriteToDisk writes the configuration to the disk. This is a thread safe operation
 // and will only allow one write at a time. Additional calls while writing are
 // queued up.
@@ -443,23 +616,33 @@ func EnsurePterodactylUser() error {
 // FromFile reads the configuration from the provided file and stores it in the
 // global singleton for this instance.
 func FromFile(path string) error {
-	b, err := ioutil.ReadFile(path)
+	c, err := readFile(path)
 	if err != nil {
 		return err
 	}
+	// Store this configuration in the global state.
+	Set(c)
+	return nil
+}
+
+// readFile parses the configuration file at the given path without storing it
+// in the global state.
+func readFile(path string) (*Configuration, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
 	c, err := NewAtPath(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Replace environment variables within the configuration file with their
 	// values from the host system.
 	b = []byte(os.ExpandEnv(string(b)))
 	if err := yaml.Unmarshal(b, c); err != nil {
-		return err
+		return nil, err
 	}
-	// Store this configuration in the global state.
-	Set(c)
-	return nil
+	return c, nil
 }
 
 // ConfigureDirectories ensures that all of the system directories exist on the
@@ -558,6 +741,48 @@ func EnableLogRotation() error {
 	return errors.Wrap(t.Execute(f, _config.System), "config: failed to write logrotate to disk")
 }
 
+// GetConsoleLogPath returns the directory that console output for the given
+// server is written to.
+func (sc *SystemConfiguration) GetConsoleLogPath(uuid string) string {
+	return path.Join(sc.LogDirectory, "/console", uuid)
+}
+
+// GetStatsHistoryPath returns the location of the file that the resource usage
+// history for the given server is persisted to.
+func (sc *SystemConfiguration) GetStatsHistoryPath(uuid string) string {
+	return path.Join(sc.RootDirectory, "/stats", uuid+".gob")
+}
+
+// GetUserIdsPath returns the location of the JSON file that tracks the user IDs
+// assigned to each server when user isolation is enabled.
+func (sc *SystemConfiguration) GetUserIdsPath() string {
+	return path.Join(sc.RootDirectory, "/uids.json")
+}
+
+// GetInstallCachePath returns the directory that downloads made by installation
+// containers are cached in.
+func (sc *SystemConfiguration) GetInstallCachePath() string {
+	return path.Join(sc.RootDirectory, "/install-cache")
+}
+
+// GetOutboxPath returns the location of the JSON file that status callbacks are
+// persisted to while the Panel cannot be reached.
+func (sc *SystemConfiguration) GetOutboxPath() string {
+	return path.Join(sc.RootDirectory, "/outbox.json")
+}
+
+// GetServerConfigCachePath returns the directory that the last configuration
+// fetched from the Panel for each server is cached in.
+func (sc *SystemConfiguration) GetServerConfigCachePath() string {
+	return path.Join(sc.RootDirectory, "/configs")
+}
+
+// GetTokenStorePath returns the location of the database that used one-time
+// token IDs and denied websocket JTIs are stored in.
+func (sc *SystemConfiguration) GetTokenStorePath() string {
+	return path.Join(sc.RootDirectory, "/tokens.db")
+}
+
 // GetStatesPath returns the location of the JSON file that tracks server states.
 func (sc *SystemConfiguration) GetStatesPath() string {
 	return path.Join(sc.RootDirectory, "/states.json")
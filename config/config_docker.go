@@ -69,7 +69,110 @@ type DockerConfiguration struct {
 	InstallerLimits struct {
 		Memory int64 `default:"1024" json:"memory" yaml:"memory"`
 		Cpu    int64 `default:"100" json:"cpu" yaml:"cpu"`
+		// The total number of processes that can be active in an installation container.
+		Pids int64 `default:"256" json:"pids" yaml:"pids"`
 	} `json:"installer_limits" yaml:"installer_limits"`
+
+	// PrivilegedInstallers is a list of egg UUIDs whose installation scripts are
+	// allowed to run in a privileged container. Every other installation container
+	// runs unprivileged with the same restrictions as a server container.
+	PrivilegedInstallers []string `json:"privileged_installers" yaml:"privileged_installers"`
+
+	// InstallerCache runs a caching proxy that installation containers download
+	// files through, so that repeated installs do not download the same files.
+	InstallerCache InstallerCacheConfiguration `json:"installer_cache" yaml:"installer_cache"`
+
+	// ContainerOptions defines the additional container options that eggs and servers
+	// are allowed to request. Anything not listed here is rejected when the container
+	// for a server is created.
+	ContainerOptions ContainerOptionsConfiguration `json:"container_options" yaml:"container_options"`
+
+	// Images defines which container images servers are allowed to use and how
+	// they are kept up to date on the node.
+	Images ImagePolicyConfiguration `json:"images" yaml:"images"`
+}
+
+// InstallerCacheConfiguration defines the caching proxy made available to the
+// installation containers. Only plain HTTP downloads can be cached, requests
+// made over HTTPS are not sent through the proxy. Most server software and
+// SteamCMD is downloaded over HTTPS, so the cache only helps eggs that download
+// files over plain HTTP.
+//
+// Only running installation containers can use the proxy, and it will not
+// connect to loopback, link-local or private addresses.
+type InstallerCacheConfiguration struct {
+	Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+	// The port the proxy listens on. The proxy is bound to the Docker network
+	// interface so that it is reachable from within the containers.
+	Port int `default:"8095" json:"port" yaml:"port"`
+
+	// The maximum size of the cache in megabytes. Once exceeded the least recently
+	// used downloads are removed.
+	MaxSize int64 `default:"10240" json:"max_size" yaml:"max_size"`
+}
+
+// ImagePolicyConfiguration defines the images that may be used by servers and
+// installation processes on the node. Patterns are matched against the full image
+// reference using shell globbing, for example "ghcr.io/pterodactyl/yolks:*".
+type ImagePolicyConfiguration struct {
+	// If any patterns are defined only images matching one of them may be used.
+	Allow []string `json:"allow" yaml:"allow"`
+
+	// Images matching any of these patterns may never be used, even if they are
+	// also allowed.
+	Deny []string `json:"deny" yaml:"deny"`
+
+	// Verify requires that images are signed before they are used. Signatures are
+	// checked with cosign against the digest of the image that was pulled.
+	Verify struct {
+		Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+		// The path to the cosign binary on the host.
+		Cosign string `default:"cosign" json:"cosign" yaml:"cosign"`
+
+		// The path to the public key that images must be signed with.
+		Key string `json:"key" yaml:"key"`
+
+		// If any patterns are defined only images matching one of them must be signed,
+		// otherwise every image is verified.
+		Images []string `json:"images" yaml:"images"`
+	} `json:"verify" yaml:"verify"`
+
+	// PrePull refreshes the images used by servers on the node once a day during the
+	// hours given, so that servers do not need to wait on a pull when they boot.
+	PrePull struct {
+		Enabled bool `default:"false" json:"enabled" yaml:"enabled"`
+
+		// The hour of the day, in the system timezone, that pulling may begin.
+		StartHour int `default:"3" json:"start_hour" yaml:"start_hour"`
+
+		// The hour of the day that pulling must begin before.
+		EndHour int `default:"5" json:"end_hour" yaml:"end_hour"`
+	} `json:"pre_pull" yaml:"pre_pull"`
+}
+
+// ContainerOptionsConfiguration defines the allowlist of additional options that
+// may be applied to server containers. Nothing is allowed by default.
+type ContainerOptionsConfiguration struct {
+	// The Linux capabilities that may be added to a container, without the "CAP_"
+	// prefix, for example "net_admin".
+	Capabilities []string `json:"capabilities" yaml:"capabilities"`
+
+	// The sysctls that may be set for a container. These are matched as glob
+	// patterns, so "net.ipv4.*" allows setting any IPv4 network sysctl.
+	Sysctls []string `json:"sysctls" yaml:"sysctls"`
+
+	// The ulimits that may be set for a container, for example "nofile".
+	Ulimits []string `json:"ulimits" yaml:"ulimits"`
+
+	// The host devices that may be passed through to a container, for example
+	// "/dev/fuse".
+	Devices []string `json:"devices" yaml:"devices"`
+
+	// The largest size of /dev/shm in megabytes that a container may request. If
+	// zero the Docker default is always used.
+	MaxShmSize int64 `json:"max_shm_size" yaml:"max_shm_size"`
 }
 
 // RegistryConfiguration defines the authentication credentials for a given
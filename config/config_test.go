@@ -0,0 +1,51 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setTestConfig stores a configuration that is written to a temporary file.
+func setTestConfig(t *testing.T) {
+	c, err := NewAtPath(filepath.Join(t.TempDir(), "config.yml"))
+	require.NoError(t, err)
+	c.AuthenticationTokenId = "id"
+	c.AuthenticationToken = "current"
+	c.PanelLocation = "https://panel.example.com"
+	Set(c)
+}
+
+func TestRotateToken(t *testing.T) {
+	setTestConfig(t)
+
+	require.NoError(t, RotateToken("new-id", "next", time.Minute))
+	assert.Equal(t, "new-id", Get().AuthenticationTokenId)
+	assert.Equal(t, []string{"next", "current"}, AuthenticationTokens())
+	assert.NotNil(t, GetPreviousJwtAlgorithm())
+
+	// The rotation is written to the disk as well.
+	c, err := readFile(Get().path)
+	require.NoError(t, err)
+	assert.Equal(t, "next", c.AuthenticationToken)
+	assert.Equal(t, "current", c.PreviousAuthenticationToken)
+
+	// Once the grace period is over the previous token is no longer accepted.
+	require.NoError(t, RotateToken("", "last", 0))
+	assert.Equal(t, "new-id", Get().AuthenticationTokenId)
+	assert.Equal(t, []string{"last"}, AuthenticationTokens())
+	assert.Nil(t, GetPreviousJwtAlgorithm())
+}
+
+func TestRotateTokenRejectsInvalidValues(t *testing.T) {
+	setTestConfig(t)
+
+	assert.Error(t, RotateToken("", "", time.Minute))
+	assert.Error(t, RotateToken("", "current", time.Minute))
+	assert.Error(t, RotateToken("", "next", -time.Second))
+	assert.Error(t, RotateToken("", "next", MaxTokenGracePeriod+time.Second))
+	assert.Equal(t, []string{"current"}, AuthenticationTokens())
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+)
+
+// liveFields are the configuration fields that are read when they are used
+// rather than once at boot, and can therefore be changed without restarting
+// Wings. Every field nested below one of these is also considered live.
+var liveFields = []string{
+	"debug",
+	"token_id",
+	"token",
+	"previous_token",
+	"previous_token_expires_at",
+	"allowed_origins",
+	"allowed_mounts",
+	"throttles",
+	"api.disable_remote_download",
+	"system.websocket_log_count",
+	"system.console_triggers",
+	"system.sftp.read_only",
+	"system.crash_detection",
+	"system.backups",
+	"system.transfers",
+	"docker.tmpfs_size",
+	"docker.container_pid_limit",
+	"docker.installer_limits",
+}
+
+// ReloadResult describes the fields that changed when a new configuration was
+// applied. Applied fields are in effect immediately, the remaining fields are
+// only used once Wings has been restarted.
+type ReloadResult struct {
+	Applied         []string `json:"applied"`
+	RequiresRestart []string `json:"requires_restart"`
+}
+
+// Validate checks that the configuration can be used by Wings, so that a bad
+// edit to the configuration file is never applied to the running instance.
+func (c *Configuration) Validate() error {
+	if c.AuthenticationToken == "" || c.AuthenticationTokenId == "" {
+		return errors.New("config: token and token_id must be set")
+	}
+	if _, err := url.ParseRequestURI(c.PanelLocation); err != nil {
+		return errors.Wrap(err, "config: remote is not a valid URL")
+	}
+	if c.Api.Port < 1 || c.Api.Port > 65535 {
+		return errors.New("config: api.port must be between 1 and 65535")
+	}
+	if c.System.Sftp.Port < 1 || c.System.Sftp.Port > 65535 {
+		return errors.New("config: system.sftp.bind_port must be between 1 and 65535")
+	}
+	if c.Api.UploadLimit < 0 {
+		return errors.New("config: api.upload_limit cannot be negative")
+	}
+	if c.Throttles.Enabled && (c.Throttles.Lines == 0 || c.Throttles.MaximumTriggerCount == 0) {
+		return errors.New("config: throttles.lines and throttles.maximum_trigger_count must be greater than zero")
+	}
+	if c.Throttles.LineResetInterval == 0 || c.Throttles.DecayInterval == 0 {
+		return errors.New("config: throttles.line_reset_interval and throttles.decay_interval must be greater than zero")
+	}
+	if c.System.CrashDetection.Timeout < 0 {
+		return errors.New("config: system.crash_detection.timeout cannot be negative")
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			continue
+		}
+		if _, err := url.ParseRequestURI(o); err != nil {
+			return errors.Wrapf(err, "config: allowed origin \"%s\" is not a valid URL", o)
+		}
+	}
+	return nil
+}
+
+// Reload reads the configuration file from the disk and applies it to the
+// running instance. If the file cannot be parsed or is not valid the running
+// configuration is left untouched.
+func Reload() (*ReloadResult, error) {
+	c, err := readFile(Get().path)
+	if err != nil {
+		return nil, err
+	}
+	return Apply(c)
+}
+
+// Apply validates the configuration and replaces the fields of the running
+// configuration that can be changed live. Fields that require a restart are
+// left as they are and returned in the result.
+func Apply(c *Configuration) (*ReloadResult, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	running := *_config
+	viaFlag := _debugViaFlag
+	mu.Unlock()
+
+	// Carry over the values that are determined when Wings boots rather than
+	// being read from the configuration file, otherwise they would always show
+	// up as having changed.
+	c.path = running.path
+	c.System.User = running.System.User
+	if c.System.Timezone == "" {
+		c.System.Timezone = running.System.Timezone
+	}
+	if d, err := filepath.EvalSymlinks(c.System.Data); err == nil && d == running.System.Data {
+		c.System.Data = running.System.Data
+	}
+	if viaFlag {
+		c.Debug = running.Debug
+	}
+
+	res := &ReloadResult{Applied: []string{}, RequiresRestart: []string{}}
+	next := running
+	diff(reflect.ValueOf(&next).Elem(), reflect.ValueOf(c).Elem(), "", func(field string, dst, src reflect.Value) {
+		if !isLive(field) {
+			res.RequiresRestart = append(res.RequiresRestart, field)
+			return
+		}
+		dst.Set(src)
+		res.Applied = append(res.Applied, field)
+	})
+	if len(res.Applied) == 0 {
+		return res, nil
+	}
+
+	Set(&next)
+	if next.Debug != running.Debug {
+		log.SetLevel(log.InfoLevel)
+		if next.Debug {
+			log.SetLevel(log.DebugLevel)
+		}
+	}
+	return res, nil
+}
+
+// diff walks two configuration structs and calls fn with the dotted YAML path
+// of every field that is not equal between them.
+func diff(dst, src reflect.Value, prefix string, fn func(field string, dst, src reflect.Value)) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if f.Type.Kind() == reflect.Struct && !isLive(name) && f.Type.NumField() > 0 && f.Type.PkgPath() != "time" {
+			diff(dst.Field(i), src.Field(i), name, fn)
+			continue
+		}
+		if !equal(dst.Field(i), src.Field(i)) {
+			fn(name, dst.Field(i), src.Field(i))
+		}
+	}
+}
+
+// equal reports whether two configuration values are the same. Empty and nil
+// slices or maps are treated as equal, as are times at the same instant.
+func equal(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Slice, reflect.Map:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	if t, ok := a.Interface().(time.Time); ok {
+		return t.Equal(b.Interface().(time.Time))
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func isLive(field string) bool {
+	for _, f := range liveFields {
+		if field == f || strings.HasPrefix(field, f+".") {
+			return true
+		}
+	}
+	return false
+}
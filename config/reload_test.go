@@ -0,0 +1,141 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLive(t *testing.T) {
+	tests := []struct {
+		field string
+		live  bool
+	}{
+		{"debug", true},
+		{"throttles", true},
+		{"throttles.lines", true},
+		{"system.crash_detection.timeout", true},
+		{"system.sftp.read_only", true},
+		{"system.sftp.bind_port", false},
+		{"system.crash", false},
+		{"api.port", false},
+		{"api.upload_limit", false},
+		{"remote", false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.live, isLive(tc.field), tc.field)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	base := Configuration{
+		Api:            ApiConfiguration{Port: 8080},
+		AllowedOrigins: []string{},
+	}
+	base.Throttles.Lines = 2000
+
+	tests := []struct {
+		name   string
+		change func(c *Configuration)
+		fields []string
+	}{
+		{"unchanged", func(c *Configuration) {}, nil},
+		{"nil and empty slices", func(c *Configuration) { c.AllowedOrigins = nil }, nil},
+		{"nested field", func(c *Configuration) { c.Api.Port = 9090 }, []string{"api.port"}},
+		{"live struct", func(c *Configuration) { c.Throttles.Lines = 10 }, []string{"throttles"}},
+		{"multiple", func(c *Configuration) {
+			c.Debug = true
+			c.System.Sftp.ReadOnly = true
+			c.PanelLocation = "https://example.com"
+		}, []string{"debug", "remote", "system.sftp.read_only"}},
+		{"same instant", func(c *Configuration) {
+			c.PreviousTokenExpiresAt = base.PreviousTokenExpiresAt.In(time.FixedZone("x", 3600))
+		}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			next := base
+			tc.change(&next)
+			var fields []string
+			diff(reflect.ValueOf(&base).Elem(), reflect.ValueOf(&next).Elem(), "", func(field string, _, _ reflect.Value) {
+				fields = append(fields, field)
+			})
+			assert.ElementsMatch(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(c *Configuration)
+		err      bool
+		applied  []string
+		restart  []string
+		validate func(t *testing.T, c *Configuration)
+	}{
+		{
+			name:    "live field",
+			change:  func(c *Configuration) { c.Throttles.Lines = 10 },
+			applied: []string{"throttles"},
+			restart: []string{},
+			validate: func(t *testing.T, c *Configuration) {
+				assert.Equal(t, uint64(10), c.Throttles.Lines)
+			},
+		},
+		{
+			name:    "restart field",
+			change:  func(c *Configuration) { c.Api.Port = 9090 },
+			applied: []string{},
+			restart: []string{"api.port"},
+			validate: func(t *testing.T, c *Configuration) {
+				assert.Equal(t, 8080, c.Api.Port)
+			},
+		},
+		{
+			name: "live and restart fields",
+			change: func(c *Configuration) {
+				c.System.Sftp.ReadOnly = true
+				c.Api.UploadLimit = 5
+			},
+			applied: []string{"system.sftp.read_only"},
+			restart: []string{"api.upload_limit"},
+			validate: func(t *testing.T, c *Configuration) {
+				assert.True(t, c.System.Sftp.ReadOnly)
+				assert.Equal(t, 100, c.Api.UploadLimit)
+			},
+		},
+		{
+			name: "validation failure",
+			change: func(c *Configuration) {
+				c.Throttles.Lines = 10
+				c.Api.Port = 0
+			},
+			err: true,
+			validate: func(t *testing.T, c *Configuration) {
+				assert.NotEqual(t, uint64(10), c.Throttles.Lines)
+				assert.Equal(t, 8080, c.Api.Port)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestConfig(t)
+			c := Get()
+			tc.change(c)
+
+			res, err := Apply(c)
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.applied, res.Applied)
+				assert.Equal(t, tc.restart, res.RequiresRestart)
+			}
+			tc.validate(t, Get())
+		})
+	}
+}
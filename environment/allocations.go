@@ -2,13 +2,21 @@ package environment
 
 import (
 	"fmt"
+	"net"
+	"sort"
 	"strconv"
+	"strings"
 
+	"emperror.dev/errors"
+	"github.com/apex/log"
 	"github.com/docker/go-connections/nat"
 
 	"github.com/pterodactyl/wings/config"
 )
 
+// The largest number of ports a single allocation range may contain.
+const maxPortRange = 100
+
 // Defines the allocations available for a given server. When using the Docker environment
 // driver these correspond to mappings for the container that allow external connections.
 type Allocations struct {
@@ -21,67 +29,208 @@ type Allocations struct {
 	} `json:"default"`
 
 	// Mappings contains all the ports that should be assigned to a given server
-	// attached to the IP they correspond to.
+	// attached to the IP they correspond to. These are bound for both TCP and UDP.
 	Mappings map[string][]int `json:"mappings"`
+
+	// Ranges contains additional contiguous ports assigned to the server which can
+	// be limited to a single protocol.
+	Ranges []PortRange `json:"ranges"`
 }
 
-// Converts the server allocation mappings into a format that can be understood by Docker. While
-// we do strive to support multiple environments, using Docker's standardized format for the
-// bindings certainly makes life a little easier for managing things.
-//
-// You'll want to use DockerBindings() if you need to re-map 127.0.0.1 to the Docker interface.
-func (a *Allocations) Bindings() nat.PortMap {
-	var out = nat.PortMap{}
+// PortRange is a contiguous range of ports on an IP that are assigned to a server.
+type PortRange struct {
+	Ip    string `json:"ip"`
+	Start int    `json:"start"`
+	// The last port in the range, inclusive. If zero only the start port is used.
+	End int `json:"end"`
+	// The protocol to bind the ports for, either "tcp", "udp", or "both". If empty
+	// both protocols are bound.
+	Protocol string `json:"protocol"`
+}
+
+// Port is a single port on the host that is bound for a server.
+type Port struct {
+	Ip       string `json:"ip"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// String returns the port formatted as "ip:port/protocol".
+func (p Port) String() string {
+	return net.JoinHostPort(p.Ip, strconv.Itoa(p.Port)) + "/" + p.Protocol
+}
+
+// Overlaps returns true if the two ports cannot both be bound at the same time.
+// An unspecified address overlaps every address of the same family.
+func (p Port) Overlaps(o Port) bool {
+	if p.Port != o.Port || p.Protocol != o.Protocol {
+		return false
+	}
+	a, b := net.ParseIP(p.Ip), net.ParseIP(o.Ip)
+	if a == nil || b == nil {
+		return p.Ip == o.Ip
+	}
+	if (a.To4() == nil) != (b.To4() == nil) {
+		return false
+	}
+	return a.Equal(b) || a.IsUnspecified() || b.IsUnspecified()
+}
+
+// normalizeIp strips the brackets from an IPv6 address and treats an empty
+// address as binding to all IPv4 interfaces.
+func normalizeIp(ip string) string {
+	ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+	if ip == "" {
+		return "0.0.0.0"
+	}
+	return ip
+}
+
+func (r PortRange) protocols() []string {
+	switch strings.ToLower(r.Protocol) {
+	case "tcp":
+		return []string{"tcp"}
+	case "udp":
+		return []string{"udp"}
+	default:
+		return []string{"tcp", "udp"}
+	}
+}
+
+func (r PortRange) end() int {
+	if r.End == 0 {
+		return r.Start
+	}
+	return r.End
+}
 
+// Ports returns every port that is bound for the server, sorted by address, port,
+// and then protocol. Invalid ports are skipped, use Validate to find them.
+func (a *Allocations) Ports() []Port {
+	var out []Port
 	for ip, ports := range a.Mappings {
 		for _, port := range ports {
 			// Skip over invalid ports.
 			if port < 1 || port > 65535 {
 				continue
 			}
+			out = append(out, Port{normalizeIp(ip), port, "tcp"}, Port{normalizeIp(ip), port, "udp"})
+		}
+	}
+	for _, r := range a.Ranges {
+		if r.Start < 1 || r.end() > 65535 || r.end() < r.Start || r.end()-r.Start >= maxPortRange {
+			continue
+		}
+		for port := r.Start; port <= r.end(); port++ {
+			for _, proto := range r.protocols() {
+				out = append(out, Port{normalizeIp(r.Ip), port, proto})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Ip != out[j].Ip {
+			return out[i].Ip < out[j].Ip
+		}
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		return out[i].Protocol < out[j].Protocol
+	})
+	return out
+}
 
-			binding := nat.PortBinding{
-				HostIP:   ip,
-				HostPort: strconv.Itoa(port),
+// Validate checks that all of the allocations for the server are valid. Ports
+// that overlap with each other only log a warning, since existing servers may
+// already have the same port assigned more than once.
+func (a *Allocations) Validate() error {
+	for ip, ports := range a.Mappings {
+		if net.ParseIP(normalizeIp(ip)) == nil {
+			return errors.New("environment: invalid allocation address: " + ip)
+		}
+		for _, port := range ports {
+			if port < 1 || port > 65535 {
+				return errors.New(fmt.Sprintf("environment: invalid allocation port: %d", port))
+			}
+		}
+	}
+	for _, r := range a.Ranges {
+		if net.ParseIP(normalizeIp(r.Ip)) == nil {
+			return errors.New("environment: invalid allocation address: " + r.Ip)
+		}
+		if r.Start < 1 || r.end() > 65535 || r.end() < r.Start {
+			return errors.New(fmt.Sprintf("environment: invalid allocation port range: %d-%d", r.Start, r.end()))
+		}
+		if r.end()-r.Start >= maxPortRange {
+			return errors.New(fmt.Sprintf("environment: allocation port range cannot contain more than %d ports", maxPortRange))
+		}
+		switch strings.ToLower(r.Protocol) {
+		case "", "both", "tcp", "udp":
+		default:
+			return errors.New("environment: invalid allocation protocol: " + r.Protocol)
+		}
+	}
+	ports := a.Ports()
+	for i := range ports {
+		for j := i + 1; j < len(ports); j++ {
+			if ports[i].Overlaps(ports[j]) {
+				log.WithFields(log.Fields{"allocation": ports[j].String(), "overlaps": ports[i].String()}).Warn("server has overlapping allocations assigned")
 			}
+		}
+	}
+	return nil
+}
 
-			tcp := nat.Port(fmt.Sprintf("%d/tcp", port))
-			udp := nat.Port(fmt.Sprintf("%d/udp", port))
+// Converts the server allocation mappings into a format that can be understood by Docker. While
+// we do strive to support multiple environments, using Docker's standardized format for the
+// bindings certainly makes life a little easier for managing things.
+//
+// You'll want to use DockerBindings() if you need to re-map 127.0.0.1 to the Docker interface.
+func (a *Allocations) Bindings() nat.PortMap {
+	var out = nat.PortMap{}
 
-			out[tcp] = append(out[tcp], binding)
-			out[udp] = append(out[udp], binding)
-		}
+	for _, p := range a.Ports() {
+		k := nat.Port(fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+		out[k] = append(out[k], nat.PortBinding{
+			HostIP:   p.Ip,
+			HostPort: strconv.Itoa(p.Port),
+		})
 	}
 
 	return out
 }
 
 // Returns the bindings for the server in a way that is supported correctly by Docker. This replaces
-// any reference to 127.0.0.1 with the IP of the pterodactyl0 network interface which will allow the
-// server to operate on a local address while still being accessible by other containers.
+// any reference to 127.0.0.1 or ::1 with the IP of the pterodactyl0 network interface which will
+// allow the server to operate on a local address while still being accessible by other containers.
 func (a *Allocations) DockerBindings() nat.PortMap {
-	iface := config.Get().Docker.Network.Interface
+	nw := config.Get().Docker.Network
+	local := map[string]string{
+		"127.0.0.1": nw.Interface,
+		"::1":       nw.Interfaces.V6.Gateway,
+	}
 
 	out := a.Bindings()
-	// Loop over all the bindings for this container, and convert any that reference 127.0.0.1
-	// to use the pterodactyl0 network interface IP, as that is the true local for what people are
-	// trying to do when creating servers.
+	// Loop over all the bindings for this container, and convert any that reference a loopback
+	// address to use the pterodactyl0 network interface IP, as that is the true local for what
+	// people are trying to do when creating servers.
 	for p, binds := range out {
-		for i, alloc := range binds {
-			if alloc.HostIP != "127.0.0.1" {
+		var kept []nat.PortBinding
+		for _, alloc := range binds {
+			iface, ok := local[alloc.HostIP]
+			if !ok {
+				kept = append(kept, alloc)
 				continue
 			}
-
 			// If using ISPN just delete the local allocation from the server.
-			if config.Get().Docker.Network.ISPN {
-				out[p] = append(out[p][:i], out[p][i+1:]...)
-			} else {
-				out[p][i] = nat.PortBinding{
-					HostIP:   iface,
-					HostPort: alloc.HostPort,
-				}
+			if nw.ISPN {
+				continue
 			}
+			kept = append(kept, nat.PortBinding{
+				HostIP:   iface,
+				HostPort: alloc.HostPort,
+			})
 		}
+		out[p] = kept
 	}
 
 	return out
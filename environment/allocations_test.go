@@ -0,0 +1,72 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestAllocationsBindings(t *testing.T) {
+	a := Allocations{
+		Mappings: map[string][]int{"10.0.0.5": {25565}},
+		Ranges: []PortRange{
+			{Ip: "10.0.0.5", Start: 2302, End: 2306, Protocol: "udp"},
+			{Ip: "[2001:db8::5]", Start: 27015},
+		},
+	}
+	require.NoError(t, a.Validate())
+
+	b := a.Bindings()
+	assert.Len(t, b, 2+5+2)
+	assert.Equal(t, []nat.PortBinding{{HostIP: "10.0.0.5", HostPort: "25565"}}, b["25565/tcp"])
+	assert.Contains(t, b, nat.Port("2304/udp"))
+	assert.NotContains(t, b, nat.Port("2304/tcp"))
+	assert.Equal(t, []nat.PortBinding{{HostIP: "2001:db8::5", HostPort: "27015"}}, b["27015/udp"])
+}
+
+func TestAllocationsDockerBindings(t *testing.T) {
+	config.Set(&config.Configuration{AuthenticationToken: "abc"})
+	config.Update(func(c *config.Configuration) {
+		c.Docker.Network.Interface = "172.18.0.1"
+		c.Docker.Network.Interfaces.V6.Gateway = "fdba:17c8:6c94::1011"
+	})
+
+	a := Allocations{Mappings: map[string][]int{"127.0.0.1": {25565}, "::1": {25566}}}
+	b := a.DockerBindings()
+	assert.Equal(t, "172.18.0.1", b["25565/tcp"][0].HostIP)
+	assert.Equal(t, "fdba:17c8:6c94::1011", b["25566/udp"][0].HostIP)
+}
+
+func TestAllocationsValidate(t *testing.T) {
+	invalid := []Allocations{
+		{Mappings: map[string][]int{"not-an-ip": {25565}}},
+		{Mappings: map[string][]int{"10.0.0.5": {0}}},
+		{Ranges: []PortRange{{Ip: "10.0.0.5", Start: 2306, End: 2302}}},
+		{Ranges: []PortRange{{Ip: "10.0.0.5", Start: 1000, End: 1100}}},
+		{Ranges: []PortRange{{Ip: "10.0.0.5", Start: 1000, Protocol: "sctp"}}},
+	}
+	for _, a := range invalid {
+		assert.Error(t, a.Validate(), "%+v", a)
+	}
+
+	// Overlapping ports are allowed since existing servers may already have them.
+	overlapping := []Allocations{
+		{Mappings: map[string][]int{"10.0.0.5": {25565, 25565}}},
+		{Mappings: map[string][]int{"10.0.0.5": {2303}}, Ranges: []PortRange{{Ip: "10.0.0.5", Start: 2302, End: 2306, Protocol: "udp"}}},
+		{Mappings: map[string][]int{"0.0.0.0": {25565}, "10.0.0.5": {25565}}},
+	}
+	for _, a := range overlapping {
+		assert.NoError(t, a.Validate(), "%+v", a)
+	}
+
+	// Different protocols and address families do not overlap.
+	a := Allocations{
+		Mappings: map[string][]int{"0.0.0.0": {25565}, "::": {25565}},
+		Ranges:   []PortRange{{Ip: "10.0.0.5", Start: 25566, Protocol: "tcp"}, {Ip: "10.0.0.5", Start: 25566, Protocol: "udp"}},
+	}
+	assert.NoError(t, a.Validate())
+}
@@ -0,0 +1,79 @@
+package environment
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+var dataDevice struct {
+	mu   sync.Mutex
+	path string
+	dev  string
+	id   string
+}
+
+// DataDevice returns the path of the block device that the server data directory
+// is stored on. If the directory is on a partition the device for the whole disk
+// is returned since IO throttling can only be applied to entire devices.
+func DataDevice() (string, error) {
+	dev, _, err := dataDeviceInfo()
+	return dev, err
+}
+
+// DataDeviceID returns the major and minor number of the block device returned
+// by DataDevice in the "major:minor" format used by cgroups.
+func DataDeviceID() (string, error) {
+	_, id, err := dataDeviceInfo()
+	return id, err
+}
+
+func dataDeviceInfo() (string, string, error) {
+	p := config.Get().System.Data
+
+	dataDevice.mu.Lock()
+	defer dataDevice.mu.Unlock()
+	if dataDevice.path == p && dataDevice.dev != "" {
+		return dataDevice.dev, dataDevice.id, nil
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat(p, &st); err != nil {
+		return "", "", errors.Wrap(err, "environment: could not stat data directory")
+	}
+	// Filesystems such as overlay, zfs and btrfs are not backed by a single block
+	// device and report a major number of 0, so there is nothing to throttle.
+	if unix.Major(st.Dev) == 0 {
+		return "", "", errors.New("environment: data directory is not stored on a block device, disk IO limits are not supported on this filesystem")
+	}
+	dev, id, err := blockDevice("/sys/dev/block", unix.Major(st.Dev), unix.Minor(st.Dev))
+	if err != nil {
+		return "", "", err
+	}
+	dataDevice.path, dataDevice.dev, dataDevice.id = p, dev, id
+	return dev, id, nil
+}
+
+func blockDevice(root string, major uint32, minor uint32) (string, string, error) {
+	sys, err := filepath.EvalSymlinks(filepath.Join(root, fmt.Sprintf("%d:%d", major, minor)))
+	if err != nil {
+		return "", "", errors.Wrap(err, "environment: could not find block device for data directory")
+	}
+	// Partitions are nested within the directory for their parent disk.
+	if _, err := os.Stat(filepath.Join(sys, "partition")); err == nil {
+		sys = filepath.Dir(sys)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(sys, "dev"))
+	if err != nil {
+		return "", "", errors.Wrap(err, "environment: could not read block device information")
+	}
+	return "/dev/" + strings.TrimSpace(filepath.Base(sys)), strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,41 @@
+package environment
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockDevice(t *testing.T) {
+	root := t.TempDir()
+	devices := filepath.Join(root, "devices", "pci0000:00", "block")
+	disk := filepath.Join(devices, "sda")
+	part := filepath.Join(disk, "sda1")
+	require.NoError(t, os.MkdirAll(part, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(disk, "dev"), []byte("8:0\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(part, "dev"), []byte("8:1\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(part, "partition"), []byte("1\n"), 0644))
+
+	links := filepath.Join(root, "dev", "block")
+	require.NoError(t, os.MkdirAll(links, 0755))
+	require.NoError(t, os.Symlink(disk, filepath.Join(links, "8:0")))
+	require.NoError(t, os.Symlink(part, filepath.Join(links, "8:1")))
+
+	// A partition resolves to the disk it is on.
+	dev, id, err := blockDevice(links, 8, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/sda", dev)
+	assert.Equal(t, "8:0", id)
+
+	dev, id, err = blockDevice(links, 8, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/sda", dev)
+	assert.Equal(t, "8:0", id)
+
+	_, _, err = blockDevice(links, 9, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package environment
+
+import (
+	"emperror.dev/errors"
+)
+
+// DataDevice returns the path of the block device that the server data directory
+// is stored on. This is only supported on Linux.
+func DataDevice() (string, error) {
+	return "", errors.New("environment: disk IO limits are only supported on Linux")
+}
+
+// DataDeviceID returns the major and minor number of the block device returned
+// by DataDevice. This is only supported on Linux.
+func DataDeviceID() (string, error) {
+	return "", errors.New("environment: disk IO limits are only supported on Linux")
+}
@@ -8,6 +8,16 @@ type Settings struct {
 	Mounts      []Mount
 	Allocations Allocations
 	Limits      Limits
+	Network     Network
+	Options     ContainerOptions
+	User        User
+}
+
+// User is the user and group the environment process runs as. If the user ID is
+// zero the default Pterodactyl user for the node is used.
+type User struct {
+	Uid int
+	Gid int
 }
 
 // Defines the actual configuration struct for the environment with all of the settings
@@ -60,6 +70,30 @@ func (c *Configuration) Allocations() Allocations {
 	return c.settings.Allocations
 }
 
+// Returns the network settings for this environment.
+func (c *Configuration) Network() Network {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.settings.Network
+}
+
+// Returns the user the environment process runs as.
+func (c *Configuration) User() User {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.settings.User
+}
+
+// Returns the additional container options requested for this environment.
+func (c *Configuration) ContainerOptions() ContainerOptions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.settings.Options
+}
+
 // Returns all of the mounts associated with this environment.
 func (c *Configuration) Mounts() []Mount {
 	c.mu.RLock()
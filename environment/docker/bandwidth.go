@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+)
+
+// applyBandwidthLimits shapes the traffic on the host side network interface of
+// the running container to match the network limits for the server. This must be
+// called each time the container is started since the interface is created along
+// with the container process.
+func (e *Environment) applyBandwidthLimits(ctx context.Context) error {
+	c, err := e.client.ContainerInspect(ctx, e.Id)
+	if err != nil {
+		return errors.Wrap(err, "environment/docker: could not inspect container")
+	}
+	if c.State == nil || c.State.Pid == 0 || c.HostConfig.NetworkMode.IsHost() {
+		return nil
+	}
+
+	// A container can be attached to more than one network, so find the interface
+	// for the network the server was created on using the MAC address of its
+	// endpoint rather than assuming it is eth0.
+	l := e.Configuration.Limits()
+	var mac string
+	if c.NetworkSettings != nil {
+		for _, name := range []string{e.networkName(), c.HostConfig.NetworkMode.NetworkName()} {
+			if ep, ok := c.NetworkSettings.Networks[name]; ok && ep != nil {
+				mac = ep.MacAddress
+				break
+			}
+		}
+	}
+	iface, err := hostInterface(c.State.Pid, mac)
+	if err != nil {
+		// If there are no limits to apply there is nothing that needs to be removed
+		// either, so don't bother reporting that the interface could not be found.
+		if l.NetworkIngress == 0 && l.NetworkEgress == 0 {
+			return nil
+		}
+		return err
+	}
+	return setBandwidth(iface, l.NetworkIngress, l.NetworkEgress)
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// hostInterface returns the name of the host side veth interface that is paired
+// with the interface inside the network namespace of the given process that has
+// the given MAC address.
+func hostInterface(pid int, mac string) (string, error) {
+	return hostInterfaceIn(fmt.Sprintf("/proc/%d/root/sys/class/net", pid), "/sys/class/net", mac)
+}
+
+// hostInterfaceIn finds the interface with the MAC address in the container
+// network directory and returns the interface it is linked to in the host one.
+func hostInterfaceIn(container string, host string, mac string) (string, error) {
+	if mac == "" {
+		return "", errors.New("environment/docker: container is not attached to the server network")
+	}
+	matches, err := filepath.Glob(filepath.Join(container, "*", "address"))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	for _, m := range matches {
+		b, err := ioutil.ReadFile(m)
+		if err != nil || !strings.EqualFold(strings.TrimSpace(string(b)), mac) {
+			continue
+		}
+		link, err := ioutil.ReadFile(filepath.Join(filepath.Dir(m), "iflink"))
+		if err != nil {
+			return "", errors.Wrap(err, "environment/docker: could not read container interface link")
+		}
+		return interfaceByIndex(host, strings.TrimSpace(string(link)))
+	}
+	return "", errors.New("environment/docker: could not find container interface with address " + mac)
+}
+
+func interfaceByIndex(root string, index string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(root, "*", "ifindex"))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	for _, m := range matches {
+		b, err := ioutil.ReadFile(m)
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(b)) == index {
+			return filepath.Base(filepath.Dir(m)), nil
+		}
+	}
+	return "", errors.New("environment/docker: could not find host interface with index " + index)
+}
+
+// setBandwidth replaces the traffic shaping on the given host interface. Traffic
+// sent out of the host interface is received by the container, so the ingress
+// limit for the server is applied as a token bucket on the root qdisc and the
+// egress limit is applied by policing the ingress qdisc of the interface. Limits
+// are in megabits per second, zero removes the limit.
+func setBandwidth(iface string, ingress int64, egress int64) error {
+	// Remove any existing shaping first, these fail if there is nothing to remove
+	// which is fine.
+	_ = tc("qdisc", "del", "dev", iface, "root")
+	_ = tc("qdisc", "del", "dev", iface, "ingress")
+
+	if ingress > 0 {
+		if err := tc("qdisc", "add", "dev", iface, "root", "tbf", "rate", rate(ingress), "burst", burst(ingress), "latency", "50ms"); err != nil {
+			return err
+		}
+	}
+	if egress > 0 {
+		if err := tc("qdisc", "add", "dev", iface, "handle", "ffff:", "ingress"); err != nil {
+			return err
+		}
+		if err := tc("filter", "add", "dev", iface, "parent", "ffff:", "protocol", "all", "prio", "1", "u32", "match", "u32", "0", "0", "police", "rate", rate(egress), "burst", burst(egress), "drop", "flowid", ":1"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rate(mbit int64) string {
+	return strconv.FormatInt(mbit, 10) + "mbit"
+}
+
+// burst returns the bucket size for a given rate, which allows for 100ms of data
+// at the full rate with a minimum of 32KiB.
+func burst(mbit int64) string {
+	b := mbit * 1_000_000 / 8 / 10
+	if b < 32*1024 {
+		b = 32 * 1024
+	}
+	return strconv.FormatInt(b, 10)
+}
+
+func tc(args ...string) error {
+	if out, err := exec.Command("tc", args...).CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "environment/docker: tc %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterfaceByIndex(t *testing.T) {
+	root := t.TempDir()
+	for name, idx := range map[string]string{"lo": "1", "eth0": "2", "veth1a2b3c": "14"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, name), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(root, name, "ifindex"), []byte(idx+"\n"), 0644))
+	}
+
+	iface, err := interfaceByIndex(root, "14")
+	require.NoError(t, err)
+	assert.Equal(t, "veth1a2b3c", iface)
+
+	_, err = interfaceByIndex(root, "15")
+	assert.Error(t, err)
+}
+
+func TestHostInterfaceIn(t *testing.T) {
+	container, host := t.TempDir(), t.TempDir()
+	for name, v := range map[string][2]string{"lo": {"00:00:00:00:00:00", "1"}, "eth0": {"02:42:ac:11:00:02", "14"}, "eth1": {"02:42:ac:12:00:03", "16"}} {
+		require.NoError(t, os.MkdirAll(filepath.Join(container, name), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(container, name, "address"), []byte(v[0]+"\n"), 0644))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(container, name, "iflink"), []byte(v[1]+"\n"), 0644))
+	}
+	for name, idx := range map[string]string{"veth14": "14", "veth16": "16"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(host, name), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(host, name, "ifindex"), []byte(idx+"\n"), 0644))
+	}
+
+	// The interface is found by the address of the endpoint rather than its name.
+	iface, err := hostInterfaceIn(container, host, "02:42:AC:12:00:03")
+	require.NoError(t, err)
+	assert.Equal(t, "veth16", iface)
+
+	iface, err = hostInterfaceIn(container, host, "02:42:ac:11:00:02")
+	require.NoError(t, err)
+	assert.Equal(t, "veth14", iface)
+
+	_, err = hostInterfaceIn(container, host, "02:42:ac:13:00:04")
+	assert.Error(t, err)
+	_, err = hostInterfaceIn(container, host, "")
+	assert.Error(t, err)
+}
+
+// Applies the shaping to one end of a veth pair created inside of a temporary
+// network namespace. This requires root and the iproute2 tools so it is skipped
+// when those are not available.
+func TestSetBandwidth(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("must be run as root")
+	}
+	for _, bin := range []string{"ip", "tc"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skip(bin + " is not available")
+		}
+	}
+
+	ns := "wings-bandwidth-test"
+	if err := exec.Command("ip", "netns", "add", ns).Run(); err != nil {
+		t.Skip("could not create network namespace: " + err.Error())
+	}
+	defer exec.Command("ip", "netns", "del", ns).Run()
+
+	run := func(args ...string) string {
+		out, err := exec.Command("ip", append([]string{"netns", "exec", ns}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(out))
+		return string(out)
+	}
+	run("ip", "link", "add", "wtest0", "type", "veth", "peer", "name", "wtest1")
+	run("ip", "link", "set", "wtest0", "up")
+
+	// Run setBandwidth itself inside of the namespace by re-executing this test
+	// binary there, since changing the namespace of the current thread is not
+	// something the test runner expects.
+	out, err := exec.Command("ip", "netns", "exec", ns, "env", "WINGS_BANDWIDTH_NS=1", os.Args[0], "-test.run", "^TestSetBandwidthInNamespace$", "-test.v").CombinedOutput()
+	require.NoError(t, err, string(out))
+	if strings.Contains(string(out), "--- SKIP") {
+		t.Skip("kernel does not support the required traffic control modules")
+	}
+
+	qd := run("tc", "qdisc", "show", "dev", "wtest0")
+	assert.Contains(t, qd, "tbf")
+	assert.Contains(t, qd, "ingress")
+	assert.Contains(t, run("tc", "filter", "show", "dev", "wtest0", "parent", "ffff:"), "police")
+}
+
+func TestSetBandwidthInNamespace(t *testing.T) {
+	if os.Getenv("WINGS_BANDWIDTH_NS") == "" {
+		t.Skip("only run from within TestSetBandwidth")
+	}
+	if err := setBandwidth("wtest0", 10, 5); err != nil && strings.Contains(err.Error(), "Failed to load TC action module") {
+		t.Skip(err.Error())
+	} else {
+		require.NoError(t, err)
+	}
+	// Applying the limits again must replace the existing ones.
+	require.NoError(t, setBandwidth("wtest0", 20, 10))
+}
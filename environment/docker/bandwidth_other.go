@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package docker
+
+import (
+	"emperror.dev/errors"
+)
+
+func hostInterface(_ int, _ string) (string, error) {
+	return "", errors.New("environment/docker: network limits are only supported on Linux")
+}
+
+func setBandwidth(_ string, _ int64, _ int64) error {
+	return errors.New("environment/docker: network limits are only supported on Linux")
+}
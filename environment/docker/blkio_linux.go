@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+// The location the cgroup hierarchies are mounted at on the host.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// setIoLimits writes the disk IO limits for a running container directly to its
+// cgroup, since Docker does not apply changes to them when a container is
+// updated. Limits of zero remove any limit that was previously applied.
+func setIoLimits(pid int, dev string, l environment.Limits) error {
+	b, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return errors.Wrap(err, "environment/docker: could not read container cgroup")
+	}
+	p, v2, err := blkioCgroup(string(b))
+	if err != nil {
+		return err
+	}
+	if v2 {
+		return writeCgroupFile(filepath.Join(cgroupRoot, p, "io.max"), ioMaxLine(dev, l))
+	}
+	dir := filepath.Join(cgroupRoot, "blkio", p)
+	for f, v := range map[string]uint64{
+		"blkio.throttle.read_bps_device":   uint64(l.IoReadLimit) * 1_000_000,
+		"blkio.throttle.write_bps_device":  uint64(l.IoWriteLimit) * 1_000_000,
+		"blkio.throttle.read_iops_device":  l.IoReadIops,
+		"blkio.throttle.write_iops_device": l.IoWriteIops,
+	} {
+		if err := writeCgroupFile(filepath.Join(dir, f), dev+" "+strconv.FormatUint(v, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blkioCgroup returns the path of the cgroup controlling block IO for a process
+// from the contents of /proc/<pid>/cgroup, and whether it is a cgroup v2 path.
+func blkioCgroup(procCgroup string) (string, bool, error) {
+	var unified string
+	scanner := bufio.NewScanner(strings.NewReader(procCgroup))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			unified = parts[2]
+			continue
+		}
+		for _, c := range strings.Split(parts[1], ",") {
+			if c == "blkio" {
+				return parts[2], false, nil
+			}
+		}
+	}
+	if unified != "" {
+		return unified, true, nil
+	}
+	return "", false, errors.New("environment/docker: could not find block IO cgroup for container")
+}
+
+// ioMaxLine returns the line written to the cgroup v2 io.max file for the limits.
+func ioMaxLine(dev string, l environment.Limits) string {
+	v := func(n uint64) string {
+		if n == 0 {
+			return "max"
+		}
+		return strconv.FormatUint(n, 10)
+	}
+	return fmt.Sprintf("%s rbps=%s wbps=%s riops=%s wiops=%s", dev, v(uint64(l.IoReadLimit)*1_000_000), v(uint64(l.IoWriteLimit)*1_000_000), v(l.IoReadIops), v(l.IoWriteIops))
+}
+
+func writeCgroupFile(p string, v string) error {
+	if err := ioutil.WriteFile(p, []byte(v), 0644); err != nil {
+		return errors.Wrap(err, "environment/docker: could not write disk IO limits to container cgroup")
+	}
+	return nil
+}
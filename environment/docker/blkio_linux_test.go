@@ -0,0 +1,32 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func TestBlkioCgroup(t *testing.T) {
+	p, v2, err := blkioCgroup("0::/system.slice/docker-abc.scope\n")
+	require.NoError(t, err)
+	assert.True(t, v2)
+	assert.Equal(t, "/system.slice/docker-abc.scope", p)
+
+	p, v2, err = blkioCgroup("12:pids:/docker/abc\n8:blkio:/docker/abc\n1:name=systemd:/docker/abc\n0::/\n")
+	require.NoError(t, err)
+	assert.False(t, v2)
+	assert.Equal(t, "/docker/abc", p)
+
+	_, _, err = blkioCgroup("")
+	assert.Error(t, err)
+}
+
+func TestIoMaxLine(t *testing.T) {
+	l := environment.Limits{IoReadLimit: 50, IoWriteIops: 200}
+	assert.Equal(t, "8:0 rbps=50000000 wbps=max riops=max wiops=200", ioMaxLine("8:0", l))
+	// Removing the limits writes "max" for every value.
+	assert.Equal(t, "8:0 rbps=max wbps=max riops=max wiops=max", ioMaxLine("8:0", environment.Limits{}))
+}
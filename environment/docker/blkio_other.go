@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package docker
+
+import (
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func setIoLimits(_ int, _ string, _ environment.Limits) error {
+	return errors.New("environment/docker: disk IO limits are only supported on Linux")
+}
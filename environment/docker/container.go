@@ -3,11 +3,9 @@ package docker
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
-	"strings"
 	"time"
 
 	"emperror.dev/errors"
@@ -25,6 +23,13 @@ import (
 
 var ErrNotAttached = errors.Sentinel("not attached to instance")
 
+// DroppedCapabilities are the Linux capabilities removed from every server
+// container, and from installation containers unless they are privileged.
+var DroppedCapabilities = []string{
+	"setpcap", "mknod", "audit_write", "net_raw", "dac_override",
+	"fowner", "fsetid", "net_bind_service", "sys_chroot", "setfcap",
+}
+
 // A custom console writer that allows us to keep a function blocked until the
 // given stream is properly closed. This does nothing special, only exists to
 // make a noop io.Writer.
@@ -75,6 +80,11 @@ func (e *Environment) Attach() error {
 		defer func() {
 			e.SetState(environment.ProcessOfflineState)
 			e.SetStream(nil)
+			// The address of the container is released once it stops, so the rules
+			// matching it must be removed before another container is given it.
+			if err := removeFirewall(e.Id); err != nil {
+				e.log().WithField("error", err).Warn("failed to remove firewall rules for container")
+			}
 		}()
 
 		go func() {
@@ -115,7 +125,8 @@ func (e *Environment) InSituUpdate() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
-	if _, err := e.client.ContainerInspect(ctx, e.Id); err != nil {
+	c, err := e.client.ContainerInspect(ctx, e.Id)
+	if err != nil {
 		// If the container doesn't exist for some reason there really isn't anything
 		// we can do to fix that in this process (it doesn't make sense at least). In those
 		// cases just return without doing anything since we still want to save the configuration
@@ -132,14 +143,43 @@ func (e *Environment) InSituUpdate() error {
 	// for removing memory limits, a container must be re-created.
 	//
 	// @see https://github.com/moby/moby/issues/41946
-	if _, err := e.client.ContainerUpdate(ctx, e.Id, container.UpdateConfig{
-		Resources: e.Configuration.Limits().AsContainerResources(),
-	}); err != nil {
+	l := e.Configuration.Limits()
+	r, err := l.AsContainerResources()
+	if err != nil {
+		return err
+	}
+	if _, err := e.client.ContainerUpdate(ctx, e.Id, container.UpdateConfig{Resources: r}); err != nil {
 		return errors.Wrap(err, "environment/docker: could not update container")
 	}
+
+	if c.State != nil && c.State.Running {
+		if err := e.applyIoLimits(c.State.Pid, l); err != nil {
+			return errors.WithStackIf(err)
+		}
+		if err := e.applyBandwidthLimits(ctx); err != nil {
+			return errors.WithStackIf(err)
+		}
+		if err := e.applyFirewall(ctx); err != nil {
+			return errors.WithStackIf(err)
+		}
+	}
 	return nil
 }
 
+// applyIoLimits writes the disk IO limits for the server to the cgroup of the
+// running container. If no limits are set and the device for the data directory
+// cannot be determined there is nothing to remove, so no error is returned.
+func (e *Environment) applyIoLimits(pid int, l environment.Limits) error {
+	dev, err := environment.DataDeviceID()
+	if err != nil {
+		if !l.HasIoLimits() {
+			return nil
+		}
+		return err
+	}
+	return setIoLimits(pid, dev, l)
+}
+
 // Create creates a new container for the server using all the data that is
 // currently available for it. If the container already exists it will be
 // returned.
@@ -153,34 +193,53 @@ func (e *Environment) Create() error {
 		return errors.Wrap(err, "environment/docker: failed to inspect container")
 	}
 
-	// Try to pull the requested image before creating the container.
-	if err := e.ensureImageExists(e.meta.Image); err != nil {
+	// Try to pull the requested image before creating the container. The container
+	// is created from the reference returned so a verified image is pinned to the
+	// digest that was verified.
+	image, err := e.ensureImageExists(e.meta.Image)
+	if err != nil {
 		return errors.WithStackIf(err)
 	}
 
 	a := e.Configuration.Allocations()
+	if err := a.Validate(); err != nil {
+		return errors.WithStackIf(err)
+	}
+	opts := e.Configuration.ContainerOptions()
+	if err := opts.Validate(); err != nil {
+		return errors.WithStackIf(err)
+	}
 
-	evs := e.Configuration.EnvironmentVariables()
+	nw, err := e.ensureNetwork(context.Background())
+	if err != nil {
+		return errors.WithStackIf(err)
+	}
+
+	// Copy the variables so that the conversion below does not write into the slice
+	// shared with the environment configuration.
+	evs := append([]string(nil), e.Configuration.EnvironmentVariables()...)
 	for i, v := range evs {
 		// Convert 127.0.0.1 to the pterodactyl0 network interface if the environment is Docker
 		// so that the server operates as expected.
 		if v == "SERVER_IP=127.0.0.1" {
 			evs[i] = "SERVER_IP=" + config.Get().Docker.Network.Interface
+		} else if v == "SERVER_IP=::1" {
+			evs[i] = "SERVER_IP=" + config.Get().Docker.Network.Interfaces.V6.Gateway
 		}
 	}
 
 	conf := &container.Config{
 		Hostname:     e.Id,
 		Domainname:   config.Get().Docker.Domainname,
-		User:         strconv.Itoa(config.Get().System.User.Uid),
+		User:         e.user(),
 		AttachStdin:  true,
 		AttachStdout: true,
 		AttachStderr: true,
 		OpenStdin:    true,
 		Tty:          true,
 		ExposedPorts: a.Exposed(),
-		Image:        strings.TrimPrefix(e.meta.Image, "~"),
-		Env:          e.Configuration.EnvironmentVariables(),
+		Image:        image,
+		Env:          evs,
 		Labels: map[string]string{
 			"Service":       "Pterodactyl",
 			"ContainerType": "server_process",
@@ -189,6 +248,11 @@ func (e *Environment) Create() error {
 
 	tmpfsSize := strconv.Itoa(int(config.Get().Docker.TmpfsSize))
 
+	resources, err := e.Configuration.Limits().AsContainerResources()
+	if err != nil {
+		return err
+	}
+
 	hostConf := &container.HostConfig{
 		PortBindings: a.DockerBindings(),
 
@@ -204,7 +268,7 @@ func (e *Environment) Create() error {
 
 		// Define resource limits for the container based on the data passed through
 		// from the Panel.
-		Resources: e.Configuration.Limits().AsContainerResources(),
+		Resources: resources,
 
 		DNS: config.Get().Docker.Network.Dns,
 
@@ -223,12 +287,10 @@ func (e *Environment) Create() error {
 
 		SecurityOpt:    []string{"no-new-privileges"},
 		ReadonlyRootfs: true,
-		CapDrop: []string{
-			"setpcap", "mknod", "audit_write", "net_raw", "dac_override",
-			"fowner", "fsetid", "net_bind_service", "sys_chroot", "setfcap",
-		},
-		NetworkMode: container.NetworkMode(config.Get().Docker.Network.Mode),
+		CapDrop:        append([]string{}, DroppedCapabilities...),
+		NetworkMode:    container.NetworkMode(nw),
 	}
+	applyContainerOptions(hostConf, opts)
 
 	// fmt.Println(hostConf)
 	if _, err := e.client.ContainerCreate(context.Background(), conf, hostConf, nil, nil, e.Id); err != nil {
@@ -238,6 +300,15 @@ func (e *Environment) Create() error {
 	return nil
 }
 
+// user returns the user the container process runs as. The group is only set
+// for isolated users, otherwise Docker's default root group is kept.
+func (e *Environment) user() string {
+	if u := e.Configuration.User(); u.Uid != 0 {
+		return fmt.Sprintf("%d:%d", u.Uid, u.Gid)
+	}
+	return strconv.Itoa(config.Get().System.User.Uid)
+}
+
 // Destroy will remove the Docker container from the server. If the container
 // is currently running it will be forcibly stopped by Docker.
 func (e *Environment) Destroy() error {
@@ -252,6 +323,11 @@ func (e *Environment) Destroy() error {
 
 	e.SetState(environment.ProcessOfflineState)
 
+	if err := removeFirewall(e.Id); err != nil {
+		e.log().WithField("error", err).Warn("failed to remove firewall rules for container")
+	}
+	e.removeNetwork(context.Background())
+
 	// Don't trigger a destroy failure if we try to delete a container that does not
 	// exist on the system. We're just a step ahead of ourselves in that case.
 	//
@@ -362,11 +438,6 @@ func (e *Environment) scanOutput(reader io.ReadCloser) {
 	go e.followOutput()
 }
 
-type imagePullStatus struct {
-	Status   string `json:"status"`
-	Progress string `json:"progress"`
-}
-
 // Pulls the image from Docker. If there is an error while pulling the image
 // from the source but the image already exists locally, we will report that
 // error to the logger but continue with the process.
@@ -375,98 +446,14 @@ type imagePullStatus struct {
 // late, and we don't need to block all the servers from booting just because
 // of that. I'd imagine in a lot of cases an outage shouldn't affect users too
 // badly. It'll at least keep existing servers working correctly if anything.
-func (e *Environment) ensureImageExists(image string) error {
-	e.Events().Publish(environment.DockerImagePullStarted, "")
-	defer e.Events().Publish(environment.DockerImagePullCompleted, "")
-
-	// Images prefixed with a ~ are local images that we do not need to try and pull.
-	if strings.HasPrefix(image, "~") {
-		return nil
-	}
-
+func (e *Environment) ensureImageExists(image string) (string, error) {
 	// Give it up to 15 minutes to pull the image. I think this should cover 99.8% of cases where an
 	// image pull might fail. I can't imagine it will ever take more than 15 minutes to fully pull
 	// an image. Let me know when I am inevitably wrong here...
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*15)
 	defer cancel()
 
-	// Get a registry auth configuration from the config.
-	var registryAuth *config.RegistryConfiguration
-	for registry, c := range config.Get().Docker.Registries {
-		if !strings.HasPrefix(image, registry) {
-			continue
-		}
-
-		log.WithField("registry", registry).Debug("using authentication for registry")
-		registryAuth = &c
-		break
-	}
-
-	// Get the ImagePullOptions.
-	imagePullOptions := types.ImagePullOptions{All: false}
-	if registryAuth != nil {
-		b64, err := registryAuth.Base64()
-		if err != nil {
-			log.WithError(err).Error("failed to get registry auth credentials")
-		}
-
-		// b64 is a string so if there is an error it will just be empty, not nil.
-		imagePullOptions.RegistryAuth = b64
-	}
-
-	out, err := e.client.ImagePull(ctx, image, imagePullOptions)
-	if err != nil {
-		images, ierr := e.client.ImageList(ctx, types.ImageListOptions{})
-		if ierr != nil {
-			// Well damn, something has gone really wrong here, just go ahead and abort there
-			// isn't much anything we can do to try and self-recover from this.
-			return errors.Wrap(ierr, "environment/docker: failed to list images")
-		}
-
-		for _, img := range images {
-			for _, t := range img.RepoTags {
-				if t != image {
-					continue
-				}
-
-				log.WithFields(log.Fields{
-					"image":        image,
-					"container_id": e.Id,
-					"err":          err.Error(),
-				}).Warn("unable to pull requested image from remote source, however the image exists locally")
-
-				// Okay, we found a matching container image, in that case just go ahead and return
-				// from this function, since there is nothing else we need to do here.
-				return nil
-			}
-		}
-
-		return errors.Wrapf(err, "environment/docker: failed to pull \"%s\" image for server", image)
-	}
-	defer out.Close()
-
-	log.WithField("image", image).Debug("pulling docker image... this could take a bit of time")
-
-	// I'm not sure what the best approach here is, but this will block execution until the image
-	// is done being pulled, which is what we need.
-	scanner := bufio.NewScanner(out)
-
-	for scanner.Scan() {
-		s := imagePullStatus{}
-		fmt.Println(scanner.Text())
-
-		if err := json.Unmarshal(scanner.Bytes(), &s); err == nil {
-			e.Events().Publish(environment.DockerImagePullStatus, s.Status+" "+s.Progress)
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return err
-	}
-
-	log.WithField("image", image).Debug("completed docker image pull")
-
-	return nil
+	return pullImage(ctx, e.client, image, e.Events())
 }
 
 func (e *Environment) convertMounts() []mount.Mount {
@@ -0,0 +1,126 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+// The nftables table that all of the server firewall chains are created in.
+const firewallTable = "inet pterodactyl"
+
+// setFirewall replaces the firewall rules for a server container with the given
+// address. Each server receives its own base chain on the forward hook which
+// sends traffic to or from the container through the ingress and egress chains
+// for it. Everything is applied in a single nftables transaction so the rules for
+// a server are never partially loaded.
+func setFirewall(id string, v4 string, v6 string, ingress []environment.FirewallRule, egress []environment.FirewallRule) error {
+	return runNft(firewallScript(id, v4, v6, ingress, egress))
+}
+
+// removeFirewall removes any firewall rules for a server. This does not return
+// an error if there are no rules loaded for the server.
+func removeFirewall(id string) error {
+	// Without nft installed there cannot be any rules loaded for the server.
+	if _, err := exec.LookPath("nft"); err != nil {
+		return nil
+	}
+	var b strings.Builder
+	// Declaring the chains first means they always exist when they are deleted,
+	// which is the only way to get an idempotent delete on older nft versions.
+	writeChains(&b, id)
+	fmt.Fprintf(&b, "delete chain %s srv_%s\n", firewallTable, id)
+	fmt.Fprintf(&b, "delete chain %s in_%s\n", firewallTable, id)
+	fmt.Fprintf(&b, "delete chain %s out_%s\n", firewallTable, id)
+	return runNft(b.String())
+}
+
+// writeChains declares the chains for a server, creating them if they do not
+// exist, and then removes any rules already in them.
+func writeChains(b *strings.Builder, id string) {
+	fmt.Fprintf(b, "add table %s\n", firewallTable)
+	// A priority of -1 runs before the filter rules Docker adds through iptables so
+	// traffic cannot be accepted by those before it is checked here.
+	fmt.Fprintf(b, "add chain %s srv_%s { type filter hook forward priority -1; policy accept; }\n", firewallTable, id)
+	fmt.Fprintf(b, "add chain %s in_%s\n", firewallTable, id)
+	fmt.Fprintf(b, "add chain %s out_%s\n", firewallTable, id)
+	for _, c := range []string{"srv_", "in_", "out_"} {
+		fmt.Fprintf(b, "flush chain %s %s%s\n", firewallTable, c, id)
+	}
+}
+
+func firewallScript(id string, v4 string, v6 string, ingress []environment.FirewallRule, egress []environment.FirewallRule) string {
+	var b strings.Builder
+	writeChains(&b, id)
+
+	for _, addr := range []struct {
+		family string
+		ip     string
+	}{{"ip", v4}, {"ip6", v6}} {
+		if addr.ip == "" {
+			continue
+		}
+		if len(ingress) > 0 {
+			fmt.Fprintf(&b, "add rule %s srv_%s %s daddr %s jump in_%s\n", firewallTable, id, addr.family, addr.ip, id)
+		}
+		if len(egress) > 0 {
+			fmt.Fprintf(&b, "add rule %s srv_%s %s saddr %s jump out_%s\n", firewallTable, id, addr.family, addr.ip, id)
+		}
+	}
+
+	writeRules(&b, "in_"+id, "saddr", ingress)
+	writeRules(&b, "out_"+id, "daddr", egress)
+	return b.String()
+}
+
+// writeRules adds the allow rules to a chain followed by a rule dropping anything
+// else. Replies to connections that were allowed are always accepted.
+func writeRules(b *strings.Builder, chain string, dir string, rules []environment.FirewallRule) {
+	if len(rules) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "add rule %s %s ct state established,related accept\n", firewallTable, chain)
+	for _, r := range rules {
+		// Only ever write the parsed network into the script. Anything that cannot
+		// be parsed is left out, which means the traffic is dropped.
+		_, ipnet, err := net.ParseCIDR(r.CIDR)
+		if err != nil {
+			continue
+		}
+		family := "ip"
+		if r.IsV6() {
+			family = "ip6"
+		}
+		rule := fmt.Sprintf("%s %s %s", family, dir, ipnet.String())
+		switch p := strings.ToLower(r.Protocol); p {
+		case "":
+		case "tcp", "udp":
+			if r.Port > 0 {
+				rule += fmt.Sprintf(" %s dport %d", p, r.Port)
+			} else {
+				rule += " meta l4proto " + p
+			}
+		default:
+			continue
+		}
+		fmt.Fprintf(b, "add rule %s %s %s accept\n", firewallTable, chain, rule)
+	}
+	fmt.Fprintf(b, "add rule %s %s drop\n", firewallTable, chain)
+}
+
+func runNft(script string) error {
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrap(err, "environment/docker: failed to apply firewall rules: "+strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func TestFirewallScript(t *testing.T) {
+	s := firewallScript("abc", "172.20.0.2", "", []environment.FirewallRule{
+		{CIDR: "10.0.0.0/8", Protocol: "tcp", Port: 25565},
+		{CIDR: "2001:db8::/32", Protocol: "udp"},
+	}, nil)
+
+	assert.Contains(t, s, "add rule inet pterodactyl srv_abc ip daddr 172.20.0.2 jump in_abc\n")
+	assert.NotContains(t, s, "jump out_abc")
+	assert.NotContains(t, s, "ip6 daddr")
+	assert.Contains(t, s, "add rule inet pterodactyl in_abc ip saddr 10.0.0.0/8 tcp dport 25565 accept\n")
+	assert.Contains(t, s, "add rule inet pterodactyl in_abc ip6 saddr 2001:db8::/32 meta l4proto udp accept\n")
+
+	// Established connections must be accepted before anything else, and everything
+	// that is not allowed must be dropped at the very end.
+	in := strings.Index(s, "add rule inet pterodactyl in_abc")
+	assert.Equal(t, in, strings.Index(s, "add rule inet pterodactyl in_abc ct state established,related accept"))
+	assert.True(t, strings.HasSuffix(s, "add rule inet pterodactyl in_abc drop\n"))
+
+	// Any existing rules for the server must be flushed before the new ones are added.
+	assert.Less(t, strings.Index(s, "flush chain inet pterodactyl in_abc"), in)
+}
+
+func TestFirewallScriptSkipsInvalidRules(t *testing.T) {
+	s := firewallScript("abc", "172.20.0.2", "", []environment.FirewallRule{
+		{CIDR: "10.0.0.0/8 accept; add rule inet filter input", Protocol: "tcp"},
+		{CIDR: "10.0.0.0/8", Protocol: "tcp dport 22 accept; #"},
+		{CIDR: "192.168.1.10/24"},
+	}, nil)
+
+	assert.NotContains(t, s, "filter input")
+	assert.NotContains(t, s, "dport 22")
+	assert.Contains(t, s, "add rule inet pterodactyl in_abc ip saddr 192.168.1.0/24 accept\n")
+	assert.True(t, strings.HasSuffix(s, "add rule inet pterodactyl in_abc drop\n"))
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package docker
+
+import (
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func setFirewall(_ string, _ string, _ string, _ []environment.FirewallRule, _ []environment.FirewallRule) error {
+	return errors.New("environment/docker: firewall rules are only supported on Linux")
+}
+
+func removeFirewall(_ string) error {
+	return nil
+}
@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/events"
+)
+
+type imagePullStatus struct {
+	Status   string `json:"status"`
+	Progress string `json:"progress"`
+}
+
+// PullImage pulls the latest version of an image, publishing the progress of
+// the pull to the event bus of every environment given. This is used to refresh
+// the images used by servers ahead of them being started. The reference that
+// containers should be created from is returned, see pullImage.
+func PullImage(ctx context.Context, image string, envs []*Environment) (string, error) {
+	cli, err := environment.Docker()
+	if err != nil {
+		return "", err
+	}
+	buses := make([]*events.EventBus, len(envs))
+	for i, e := range envs {
+		buses[i] = e.Events()
+	}
+	return pullImage(ctx, cli, image, buses...)
+}
+
+// pullImage checks that the image is allowed on the node and pulls it. If there
+// is an error pulling the image but it exists locally the local image is used.
+// If signature verification is enabled the image is only returned as usable once
+// the signature for the digest that was pulled has been verified.
+//
+// The returned reference is what the container should be created from. When the
+// image was verified this is the digest that was verified so that the tag cannot
+// be moved to a different image between verification and the container being
+// created.
+func pullImage(ctx context.Context, cli *client.Client, image string, buses ...*events.EventBus) (string, error) {
+	publish := func(topic string, data string) {
+		for _, b := range buses {
+			b.Publish(topic, data)
+		}
+	}
+
+	if err := environment.CheckImagePolicy(image); err != nil {
+		return "", err
+	}
+
+	publish(environment.DockerImagePullStarted, "")
+	defer publish(environment.DockerImagePullCompleted, "")
+
+	// Images prefixed with a ~ are local images that we do not need to try and pull.
+	if strings.HasPrefix(image, "~") {
+		return strings.TrimPrefix(image, "~"), nil
+	}
+
+	// Get a registry auth configuration from the config.
+	var registryAuth *config.RegistryConfiguration
+	for registry, c := range config.Get().Docker.Registries {
+		if !strings.HasPrefix(image, registry) {
+			continue
+		}
+
+		log.WithField("registry", registry).Debug("using authentication for registry")
+		registryAuth = &c
+		break
+	}
+
+	// Get the ImagePullOptions.
+	imagePullOptions := types.ImagePullOptions{All: false}
+	if registryAuth != nil {
+		b64, err := registryAuth.Base64()
+		if err != nil {
+			log.WithError(err).Error("failed to get registry auth credentials")
+		}
+
+		// b64 is a string so if there is an error it will just be empty, not nil.
+		imagePullOptions.RegistryAuth = b64
+	}
+
+	out, err := cli.ImagePull(ctx, image, imagePullOptions)
+	if err != nil {
+		images, ierr := cli.ImageList(ctx, types.ImageListOptions{})
+		if ierr != nil {
+			// Well damn, something has gone really wrong here, just go ahead and abort there
+			// isn't much anything we can do to try and self-recover from this.
+			return "", errors.Wrap(ierr, "environment/docker: failed to list images")
+		}
+
+		for _, img := range images {
+			for _, t := range img.RepoTags {
+				if t != image {
+					continue
+				}
+
+				log.WithFields(log.Fields{
+					"image": image,
+					"err":   err.Error(),
+				}).Warn("unable to pull requested image from remote source, however the image exists locally")
+
+				// Okay, we found a matching container image, in that case just go ahead and return
+				// from this function, since there is nothing else we need to do here.
+				return verifyImage(ctx, cli, image)
+			}
+		}
+
+		return "", errors.Wrapf(err, "environment/docker: failed to pull \"%s\" image for server", image)
+	}
+	defer out.Close()
+
+	log.WithField("image", image).Debug("pulling docker image... this could take a bit of time")
+
+	// I'm not sure what the best approach here is, but this will block execution until the image
+	// is done being pulled, which is what we need.
+	scanner := bufio.NewScanner(out)
+
+	for scanner.Scan() {
+		s := imagePullStatus{}
+		log.WithField("image", image).Debug(scanner.Text())
+
+		if err := json.Unmarshal(scanner.Bytes(), &s); err == nil {
+			publish(environment.DockerImagePullStatus, s.Status+" "+s.Progress)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	log.WithField("image", image).Debug("completed docker image pull")
+
+	return verifyImage(ctx, cli, image)
+}
+
+// verifyImage verifies the signature of the local copy of an image if that is
+// required by the node configuration, returning the digest reference that was
+// verified. If no verification is required the image is returned unchanged.
+func verifyImage(ctx context.Context, cli *client.Client, image string) (string, error) {
+	if !environment.RequiresVerification(image) {
+		return image, nil
+	}
+	i, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", errors.Wrap(err, "environment/docker: failed to inspect image")
+	}
+	ref := imageDigestRef(image, i.RepoDigests)
+	if ref == "" {
+		return "", errors.New("environment/docker: image does not have a digest to verify: " + image)
+	}
+	vctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	if err := environment.VerifyImageSignature(vctx, ref); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// imageDigestRef returns the digest reference for the repository of the image
+// from the digests reported for it by Docker.
+func imageDigestRef(image string, digests []string) string {
+	repo := image
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	for _, d := range digests {
+		if strings.HasPrefix(d, repo+"@") {
+			return d
+		}
+	}
+	// Images from Docker Hub are reported without the registry and library prefix.
+	for _, d := range digests {
+		if name := strings.SplitN(d, "@", 2)[0]; strings.HasSuffix(repo, "/"+name) {
+			return d
+		}
+	}
+	return ""
+}
@@ -0,0 +1,17 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageDigestRef(t *testing.T) {
+	digests := []string{"debian@sha256:aaa", "ghcr.io/pterodactyl/yolks@sha256:bbb", "localhost:5000/yolks@sha256:ccc"}
+
+	assert.Equal(t, "ghcr.io/pterodactyl/yolks@sha256:bbb", imageDigestRef("ghcr.io/pterodactyl/yolks:java_17", digests))
+	assert.Equal(t, "localhost:5000/yolks@sha256:ccc", imageDigestRef("localhost:5000/yolks", digests))
+	assert.Equal(t, "debian@sha256:aaa", imageDigestRef("debian:bullseye", digests))
+	assert.Equal(t, "debian@sha256:aaa", imageDigestRef("docker.io/library/debian:bullseye", digests))
+	assert.Empty(t, imageDigestRef("alpine:latest", digests))
+}
@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// networkName returns the name of the Docker network the container for this
+// server should be attached to.
+func (e *Environment) networkName() string {
+	n := e.Configuration.Network()
+	switch n.Mode {
+	case environment.NetworkModeIsolated:
+		return "pterodactyl_srv_" + e.Id
+	case environment.NetworkModeGroup:
+		return "pterodactyl_grp_" + n.Group
+	default:
+		return config.Get().Docker.Network.Mode
+	}
+}
+
+// ensureNetwork creates the isolated or group network for the server if it does
+// not already exist and returns the name of the network to attach the container
+// to. Servers using the default network are attached to the network configured
+// for the node which is created when Wings boots.
+func (e *Environment) ensureNetwork(ctx context.Context) (string, error) {
+	n := e.Configuration.Network()
+	if err := n.Validate(); err != nil {
+		return "", err
+	}
+	name := e.networkName()
+	if n.Mode != environment.NetworkModeIsolated && n.Mode != environment.NetworkModeGroup {
+		return name, nil
+	}
+
+	if _, err := e.client.NetworkInspect(ctx, name, types.NetworkInspectOptions{}); err == nil {
+		return name, nil
+	} else if !client.IsErrNotFound(err) {
+		return "", errors.Wrap(err, "environment/docker: failed to inspect network")
+	}
+
+	e.log().WithField("network", name).Info("creating network for server container")
+	_, err := e.client.NetworkCreate(ctx, name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+		Labels: map[string]string{
+			"Service": "Pterodactyl",
+		},
+		Options: map[string]string{
+			// Servers in the same group need to be able to reach each other, there is
+			// only ever one container on an isolated network so this has no effect there.
+			"com.docker.network.bridge.enable_icc":           "true",
+			"com.docker.network.bridge.enable_ip_masquerade": "true",
+			"com.docker.network.driver.mtu":                  "1500",
+		},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "environment/docker: failed to create network")
+	}
+	return name, nil
+}
+
+// removeNetwork removes the isolated or group network for the server. Group
+// networks are only removed once no other containers are attached to them, which
+// Docker enforces for us, so any error here is simply logged.
+func (e *Environment) removeNetwork(ctx context.Context) {
+	n := e.Configuration.Network()
+	if n.Mode != environment.NetworkModeIsolated && n.Mode != environment.NetworkModeGroup {
+		return
+	}
+	if err := e.client.NetworkRemove(ctx, e.networkName()); err != nil && !client.IsErrNotFound(err) {
+		e.log().WithField("network", e.networkName()).WithField("error", err).Debug("could not remove server network")
+	}
+}
+
+// applyFirewall loads the ingress and egress rules for the server into nftables
+// on the host. The rules match on the address of the container so this must be
+// called each time the container is started.
+func (e *Environment) applyFirewall(ctx context.Context) error {
+	n := e.Configuration.Network()
+	// The rules are written into an nftables script, so never load anything that
+	// has not been validated. This can be reached with a configuration from a sync
+	// that never went through ensureNetwork.
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	if !n.HasFirewall() {
+		return removeFirewall(e.Id)
+	}
+
+	c, err := e.client.ContainerInspect(ctx, e.Id)
+	if err != nil {
+		return errors.Wrap(err, "environment/docker: could not inspect container")
+	}
+	if c.NetworkSettings == nil || c.NetworkSettings.Networks[e.networkName()] == nil {
+		return errors.New("environment/docker: container is not attached to the expected network")
+	}
+	ep := c.NetworkSettings.Networks[e.networkName()]
+	if ep.IPAddress == "" && ep.GlobalIPv6Address == "" {
+		return errors.New("environment/docker: container has not been assigned an address")
+	}
+	return setFirewall(e.Id, ep.IPAddress, ep.GlobalIPv6Address, n.Ingress, n.Egress)
+}
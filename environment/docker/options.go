@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+// applyContainerOptions merges the additional options requested for a server
+// into the host configuration for its container. The options must already have
+// been validated against the node configuration.
+func applyContainerOptions(h *container.HostConfig, o environment.ContainerOptions) {
+	for _, c := range o.Capabilities {
+		c = strings.TrimPrefix(strings.ToLower(c), "cap_")
+		// Docker applies the drop list after the add list, so anything that has been
+		// requested must be removed from the default drop list for it to take effect.
+		drop := h.CapDrop[:0]
+		for _, d := range h.CapDrop {
+			if d != c {
+				drop = append(drop, d)
+			}
+		}
+		h.CapDrop = drop
+		h.CapAdd = append(h.CapAdd, c)
+	}
+	if len(o.Sysctls) > 0 {
+		h.Sysctls = make(map[string]string, len(o.Sysctls))
+		for k, v := range o.Sysctls {
+			h.Sysctls[k] = v
+		}
+	}
+	for _, u := range o.Ulimits {
+		h.Ulimits = append(h.Ulimits, &units.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	if o.ShmSize > 0 {
+		h.ShmSize = o.ShmSize * 1024 * 1024
+	}
+	for _, d := range o.DevicePaths() {
+		h.Devices = append(h.Devices, container.DeviceMapping{
+			PathOnHost:        d,
+			PathInContainer:   d,
+			CgroupPermissions: "rwm",
+		})
+	}
+}
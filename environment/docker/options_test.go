@@ -0,0 +1,21 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func TestApplyContainerOptionsDevices(t *testing.T) {
+	h := &container.HostConfig{}
+	applyContainerOptions(h, environment.ContainerOptions{Devices: []string{"/dev/../dev/fuse/"}})
+
+	// The device is mapped using the same cleaned path that was validated.
+	require.Len(t, h.Devices, 1)
+	assert.Equal(t, "/dev/fuse", h.Devices[0].PathOnHost)
+	assert.Equal(t, "/dev/fuse", h.Devices[0].PathInContainer)
+}
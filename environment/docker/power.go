@@ -112,6 +112,22 @@ func (e *Environment) Start() error {
 		return errors.WrapIf(err, "environment/docker: failed to start container")
 	}
 
+	// Network limits can only be applied once the container is running since the
+	// interface for it does not exist until then. A failure here should not stop
+	// the server from booting.
+	if err := e.applyBandwidthLimits(ctx); err != nil {
+		e.log().WithField("error", err).Warn("failed to apply network limits to container")
+	}
+
+	// Unlike the limits above the firewall rules are a security boundary, so if they
+	// cannot be applied the container is killed rather than left running without them.
+	if err := e.applyFirewall(ctx); err != nil {
+		if kerr := e.client.ContainerKill(ctx, e.Id, "SIGKILL"); kerr != nil {
+			e.log().WithField("error", kerr).Error("failed to kill container after firewall rules could not be applied")
+		}
+		return errors.WithStackIf(err)
+	}
+
 	// No errors, good to continue through.
 	sawError = false
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"math"
+	"strings"
 
 	"emperror.dev/errors"
 	"github.com/docker/docker/api/types"
@@ -28,6 +29,7 @@ func (e *Environment) pollResources(ctx context.Context) error {
 	}
 	defer stats.Body.Close()
 
+	var prev types.StatsJSON
 	dec := json.NewDecoder(stats.Body)
 	for {
 		select {
@@ -61,6 +63,9 @@ func (e *Environment) pollResources(ctx context.Context) error {
 				st.Network.RxBytes += nw.RxBytes
 				st.Network.TxBytes += nw.TxBytes
 			}
+			st.Network.RxRate, st.Network.TxRate = calculateNetworkRate(prev, v, st.Network)
+			st.Io = calculateIoStats(prev, v, e.Configuration.Limits())
+			prev = v
 
 			if b, err := json.Marshal(st); err != nil {
 				e.log().WithField("error", err).Warn("error while marshaling stats object for environment")
@@ -115,3 +120,69 @@ func calculateDockerAbsoluteCpu(pStats types.CPUStats, stats types.CPUStats) flo
 
 	return math.Round(percent*1000) / 1000
 }
+
+// Calculates the network throughput of the container in bytes per second using
+// the totals from the previous stats reading. If there is no previous reading, or
+// the counters were reset, the rate is reported as zero.
+func calculateNetworkRate(prev types.StatsJSON, cur types.StatsJSON, n environment.NetworkStats) (uint64, uint64) {
+	elapsed := cur.Read.Sub(prev.Read).Seconds()
+	if prev.Read.IsZero() || elapsed <= 0 {
+		return 0, 0
+	}
+	var rx, tx uint64
+	for _, nw := range prev.Networks {
+		rx += nw.RxBytes
+		tx += nw.TxBytes
+	}
+	if n.RxBytes < rx || n.TxBytes < tx {
+		return 0, 0
+	}
+	return uint64(float64(n.RxBytes-rx) / elapsed), uint64(float64(n.TxBytes-tx) / elapsed)
+}
+
+// Returns the total bytes and operations read and written by the container along
+// with the current rates. The container is considered to be throttled if any of
+// the rates are within 90% of the corresponding limit for the server.
+//
+// cgroup v1 reports the operations as "Read" and "Write" while v2 uses lowercase
+// names, so both are handled here.
+func calculateIoStats(prev types.StatsJSON, cur types.StatsJSON, l environment.Limits) environment.IoStats {
+	sum := func(entries []types.BlkioStatEntry) (r uint64, w uint64) {
+		for _, e := range entries {
+			switch strings.ToLower(e.Op) {
+			case "read":
+				r += e.Value
+			case "write":
+				w += e.Value
+			}
+		}
+		return r, w
+	}
+
+	var st environment.IoStats
+	st.ReadBytes, st.WriteBytes = sum(cur.BlkioStats.IoServiceBytesRecursive)
+	st.ReadOps, st.WriteOps = sum(cur.BlkioStats.IoServicedRecursive)
+
+	elapsed := cur.Read.Sub(prev.Read).Seconds()
+	if prev.Read.IsZero() || elapsed <= 0 {
+		return st
+	}
+	rate := func(cur uint64, prev uint64) uint64 {
+		if cur < prev {
+			return 0
+		}
+		return uint64(float64(cur-prev) / elapsed)
+	}
+	pr, pw := sum(prev.BlkioStats.IoServiceBytesRecursive)
+	pro, pwo := sum(prev.BlkioStats.IoServicedRecursive)
+	st.ReadRate, st.WriteRate = rate(st.ReadBytes, pr), rate(st.WriteBytes, pw)
+
+	near := func(v uint64, limit uint64) bool {
+		return limit > 0 && float64(v) >= float64(limit)*0.9
+	}
+	st.Throttled = near(st.ReadRate, uint64(l.IoReadLimit)*1_000_000) ||
+		near(st.WriteRate, uint64(l.IoWriteLimit)*1_000_000) ||
+		near(rate(st.ReadOps, pro), l.IoReadIops) ||
+		near(rate(st.WriteOps, pwo), l.IoWriteIops)
+	return st
+}
@@ -0,0 +1,67 @@
+package environment
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path"
+	"strings"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// ErrImageNotAllowed is returned when an image is not permitted by the image
+// policy for the node.
+var ErrImageNotAllowed = errors.Sentinel("environment: image is not allowed on this node")
+
+func matchesAny(patterns []string, image string) bool {
+	for _, p := range patterns {
+		if m, _ := path.Match(p, image); m {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckImagePolicy returns an error if the image is not allowed to be used on
+// the node. Local images prefixed with "~" are checked without the prefix.
+func CheckImagePolicy(image string) error {
+	image = strings.TrimPrefix(image, "~")
+	p := config.Get().Docker.Images
+	if matchesAny(p.Deny, image) {
+		return errors.WithDetails(errors.Wrap(ErrImageNotAllowed, "image is denied: "+image), "image", image)
+	}
+	if len(p.Allow) > 0 && !matchesAny(p.Allow, image) {
+		return errors.WithDetails(errors.Wrap(ErrImageNotAllowed, "image is not in the allowlist: "+image), "image", image)
+	}
+	return nil
+}
+
+// RequiresVerification returns true if the signature of the image must be
+// verified before it is used.
+func RequiresVerification(image string) bool {
+	v := config.Get().Docker.Images.Verify
+	if !v.Enabled || strings.HasPrefix(image, "~") {
+		return false
+	}
+	return len(v.Images) == 0 || matchesAny(v.Images, image)
+}
+
+// VerifyImageSignature checks the signature for an image reference using
+// cosign. The reference should include the digest of the image so that the image
+// verified is exactly the one that will be used.
+func VerifyImageSignature(ctx context.Context, ref string) error {
+	v := config.Get().Docker.Images.Verify
+	if v.Key == "" {
+		return errors.New("environment: image verification is enabled but no key is configured")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, v.Cosign, "verify", "--key", v.Key, ref)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.WithDetails(errors.Wrap(err, "environment: image signature could not be verified: "+strings.TrimSpace(stderr.String())), "image", ref)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestCheckImagePolicy(t *testing.T) {
+	config.Set(&config.Configuration{AuthenticationToken: "abc"})
+	assert.NoError(t, CheckImagePolicy("debian:bullseye"))
+
+	config.Update(func(c *config.Configuration) {
+		c.Docker.Images.Allow = []string{"ghcr.io/pterodactyl/yolks:*", "ghcr.io/pterodactyl/installers:*"}
+		c.Docker.Images.Deny = []string{"ghcr.io/pterodactyl/yolks:java_8"}
+	})
+	assert.NoError(t, CheckImagePolicy("ghcr.io/pterodactyl/yolks:java_17"))
+	assert.NoError(t, CheckImagePolicy("~ghcr.io/pterodactyl/installers:debian"))
+
+	err := CheckImagePolicy("ghcr.io/pterodactyl/yolks:java_8")
+	assert.ErrorIs(t, err, ErrImageNotAllowed)
+	err = CheckImagePolicy("docker.io/library/debian:bullseye")
+	assert.ErrorIs(t, err, ErrImageNotAllowed)
+}
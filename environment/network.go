@@ -0,0 +1,98 @@
+package environment
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// The network modes that can be selected for a server.
+const (
+	// NetworkModeDefault places the server on the network defined in the Wings
+	// configuration that is shared by every server on the node.
+	NetworkModeDefault = "default"
+	// NetworkModeIsolated places the server on its own network so that it cannot
+	// reach any other server on the node.
+	//
+	// Every isolated network takes a subnet from the default address pools of the
+	// Docker daemon, which only contain 31 networks unless "default-address-pools"
+	// is configured in daemon.json. Nodes with more isolated servers than that need
+	// larger pools, for example a /16 split into /24 networks, otherwise creating
+	// the network for a server fails.
+	NetworkModeIsolated = "isolated"
+	// NetworkModeGroup places the server on a network shared only with the other
+	// servers that use the same group name.
+	NetworkModeGroup = "group"
+)
+
+var networkGroupRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,32}$`)
+
+// Network defines the network a server is attached to and the firewall rules that
+// apply to traffic for it.
+type Network struct {
+	Mode string `json:"mode"`
+
+	// The name of the group network to attach the server to when the mode is
+	// NetworkModeGroup.
+	Group string `json:"group"`
+
+	// The sources that are allowed to connect to the server and the destinations
+	// that the server is allowed to connect to. If a list is empty traffic in that
+	// direction is not restricted, otherwise anything not matching a rule is dropped.
+	// Replies to allowed connections are always permitted.
+	Ingress []FirewallRule `json:"ingress"`
+	Egress  []FirewallRule `json:"egress"`
+}
+
+// FirewallRule allows traffic to or from a range of addresses.
+type FirewallRule struct {
+	// The address or range of addresses in CIDR notation the rule applies to.
+	CIDR string `json:"cidr"`
+
+	// The protocol of the traffic, either "tcp" or "udp". If empty the rule applies
+	// to all traffic and the port is ignored.
+	Protocol string `json:"protocol"`
+
+	// The destination port of the traffic, or 0 for any port.
+	Port int `json:"port"`
+}
+
+// IsV6 returns true if the rule applies to IPv6 addresses.
+func (r FirewallRule) IsV6() bool {
+	ip, _, err := net.ParseCIDR(r.CIDR)
+	return err == nil && ip.To4() == nil
+}
+
+// Validate ensures the network configuration for a server is valid.
+func (n Network) Validate() error {
+	switch n.Mode {
+	case "", NetworkModeDefault, NetworkModeIsolated:
+	case NetworkModeGroup:
+		if !networkGroupRegex.MatchString(n.Group) {
+			return errors.New("environment: network group name must be 1 to 32 alphanumeric characters, dashes, or underscores")
+		}
+	default:
+		return errors.New("environment: invalid network mode: " + n.Mode)
+	}
+	for _, r := range append(append([]FirewallRule{}, n.Ingress...), n.Egress...) {
+		if _, _, err := net.ParseCIDR(r.CIDR); err != nil {
+			return errors.Wrap(err, "environment: invalid firewall rule address")
+		}
+		switch strings.ToLower(r.Protocol) {
+		case "", "tcp", "udp":
+		default:
+			return errors.New("environment: invalid firewall rule protocol: " + r.Protocol)
+		}
+		if r.Port < 0 || r.Port > 65535 {
+			return errors.New("environment: invalid firewall rule port")
+		}
+	}
+	return nil
+}
+
+// HasFirewall returns true if any firewall rules are defined for the server.
+func (n Network) HasFirewall() bool {
+	return len(n.Ingress) > 0 || len(n.Egress) > 0
+}
@@ -0,0 +1,21 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkValidate(t *testing.T) {
+	assert.NoError(t, Network{}.Validate())
+	assert.NoError(t, Network{Mode: NetworkModeIsolated}.Validate())
+	assert.NoError(t, Network{Mode: NetworkModeGroup, Group: "proxy_backend-1"}.Validate())
+	assert.NoError(t, Network{Egress: []FirewallRule{{CIDR: "0.0.0.0/0", Protocol: "TCP", Port: 443}}}.Validate())
+
+	assert.Error(t, Network{Mode: "bridge"}.Validate())
+	assert.Error(t, Network{Mode: NetworkModeGroup}.Validate())
+	assert.Error(t, Network{Mode: NetworkModeGroup, Group: "../nw"}.Validate())
+	assert.Error(t, Network{Ingress: []FirewallRule{{CIDR: "10.0.0.1"}}}.Validate())
+	assert.Error(t, Network{Ingress: []FirewallRule{{CIDR: "10.0.0.0/8", Protocol: "icmp"}}}.Validate())
+	assert.Error(t, Network{Ingress: []FirewallRule{{CIDR: "10.0.0.0/8", Port: 70000}}}.Validate())
+}
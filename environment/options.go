@@ -0,0 +1,151 @@
+package environment
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// ContainerOptions defines additional options that an egg or server requests be
+// applied to the container. Every option must be allowed by the node config in
+// order for the container to be created.
+type ContainerOptions struct {
+	// Linux capabilities to add to the container, without the "CAP_" prefix.
+	Capabilities []string `json:"capabilities"`
+
+	// Namespaced kernel parameters to set within the container.
+	Sysctls map[string]string `json:"sysctls"`
+
+	// Resource limits for the processes running in the container.
+	Ulimits []Ulimit `json:"ulimits"`
+
+	// The size of /dev/shm within the container in megabytes. If zero the Docker
+	// default is used.
+	ShmSize int64 `json:"shm_size"`
+
+	// The host devices to make available within the container at the same path.
+	Devices []string `json:"devices"`
+}
+
+// Ulimit is a single resource limit applied to the processes in a container.
+type Ulimit struct {
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+// Merge returns the combination of both sets of options. Values set in the
+// options passed in take precedence over the ones they are merged into.
+func (o ContainerOptions) Merge(other ContainerOptions) ContainerOptions {
+	out := ContainerOptions{
+		Capabilities: append(append([]string{}, o.Capabilities...), other.Capabilities...),
+		Sysctls:      make(map[string]string, len(o.Sysctls)+len(other.Sysctls)),
+		ShmSize:      o.ShmSize,
+		Devices:      append(append([]string{}, o.Devices...), other.Devices...),
+	}
+	for k, v := range o.Sysctls {
+		out.Sysctls[k] = v
+	}
+	for k, v := range other.Sysctls {
+		out.Sysctls[k] = v
+	}
+	ulimits := make(map[string]int)
+	for _, u := range append(append([]Ulimit{}, o.Ulimits...), other.Ulimits...) {
+		if i, ok := ulimits[u.Name]; ok {
+			out.Ulimits[i] = u
+			continue
+		}
+		ulimits[u.Name] = len(out.Ulimits)
+		out.Ulimits = append(out.Ulimits, u)
+	}
+	if other.ShmSize > 0 {
+		out.ShmSize = other.ShmSize
+	}
+	return out
+}
+
+// Validate ensures that every option requested is allowed by the node config.
+func (o ContainerOptions) Validate() error {
+	allowed := config.Get().Docker.ContainerOptions
+
+	for _, c := range o.Capabilities {
+		ok := false
+		for _, a := range allowed.Capabilities {
+			if capabilityName(a) == capabilityName(c) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return errors.New("environment: capability is not allowed on this node: " + c)
+		}
+	}
+	for k := range o.Sysctls {
+		ok := false
+		for _, p := range allowed.Sysctls {
+			if m, _ := path.Match(p, k); m {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return errors.New("environment: sysctl is not allowed on this node: " + k)
+		}
+	}
+	for _, u := range o.Ulimits {
+		if !containsFold(allowed.Ulimits, u.Name) {
+			return errors.New("environment: ulimit is not allowed on this node: " + u.Name)
+		}
+		if u.Soft < 0 || u.Hard < 0 || u.Soft > u.Hard {
+			return errors.New("environment: invalid values for ulimit: " + u.Name)
+		}
+	}
+	if o.ShmSize < 0 || o.ShmSize > allowed.MaxShmSize {
+		return errors.New(fmt.Sprintf("environment: shm size cannot be larger than %dMB on this node", allowed.MaxShmSize))
+	}
+	for _, d := range o.DevicePaths() {
+		if !contains(allowed.Devices, d) {
+			return errors.New("environment: device is not allowed on this node: " + d)
+		}
+	}
+	return nil
+}
+
+// DevicePaths returns the cleaned paths of the devices requested for the server.
+// These are the paths that are validated and mapped into the container, so that
+// a path such as "/dev/../dev/fuse" is checked as the device it resolves to.
+func (o ContainerOptions) DevicePaths() []string {
+	out := make([]string, len(o.Devices))
+	for i, d := range o.Devices {
+		out[i] = path.Clean(d)
+	}
+	return out
+}
+
+// capabilityName normalizes a capability to the lowercase name without the
+// "CAP_" prefix that is used by the cap drop list for containers.
+func capabilityName(c string) string {
+	return strings.TrimPrefix(strings.ToLower(c), "cap_")
+}
+
+func containsFold(s []string, v string) bool {
+	for _, i := range s {
+		if strings.EqualFold(i, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
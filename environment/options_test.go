@@ -0,0 +1,63 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestContainerOptionsValidate(t *testing.T) {
+	config.Set(&config.Configuration{AuthenticationToken: "abc"})
+	config.Update(func(c *config.Configuration) {
+		c.Docker.ContainerOptions = config.ContainerOptionsConfiguration{
+			Capabilities: []string{"SYS_ADMIN"},
+			Sysctls:      []string{"net.ipv4.*"},
+			Ulimits:      []string{"nofile"},
+			Devices:      []string{"/dev/fuse"},
+			MaxShmSize:   256,
+		}
+	})
+
+	assert.NoError(t, ContainerOptions{}.Validate())
+	assert.NoError(t, ContainerOptions{
+		Capabilities: []string{"cap_sys_admin"},
+		Sysctls:      map[string]string{"net.ipv4.ip_unprivileged_port_start": "0"},
+		Ulimits:      []Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}},
+		ShmSize:      256,
+		Devices:      []string{"/dev/fuse"},
+	}.Validate())
+
+	assert.Error(t, ContainerOptions{Capabilities: []string{"net_admin"}}.Validate())
+	assert.Error(t, ContainerOptions{Sysctls: map[string]string{"kernel.shmmax": "1"}}.Validate())
+	assert.Error(t, ContainerOptions{Ulimits: []Ulimit{{Name: "nproc", Soft: 1, Hard: 1}}}.Validate())
+	assert.Error(t, ContainerOptions{Ulimits: []Ulimit{{Name: "nofile", Soft: 2, Hard: 1}}}.Validate())
+	assert.Error(t, ContainerOptions{ShmSize: 512}.Validate())
+	assert.Error(t, ContainerOptions{Devices: []string{"/dev/sda"}}.Validate())
+	assert.Error(t, ContainerOptions{Devices: []string{"/dev/fuse/../sda"}}.Validate())
+	assert.NoError(t, ContainerOptions{Devices: []string{"/dev/../dev/fuse/"}}.Validate())
+}
+
+func TestContainerOptionsDevicePaths(t *testing.T) {
+	o := ContainerOptions{Devices: []string{"/dev/fuse", "/dev/../dev/fuse/", "/dev//net/tun"}}
+	assert.Equal(t, []string{"/dev/fuse", "/dev/fuse", "/dev/net/tun"}, o.DevicePaths())
+}
+
+func TestContainerOptionsMerge(t *testing.T) {
+	egg := ContainerOptions{
+		Sysctls: map[string]string{"a": "1", "b": "1"},
+		Ulimits: []Ulimit{{Name: "nofile", Soft: 1, Hard: 1}},
+		ShmSize: 64,
+	}
+	o := egg.Merge(ContainerOptions{
+		Sysctls: map[string]string{"b": "2"},
+		Ulimits: []Ulimit{{Name: "nofile", Soft: 2, Hard: 2}},
+	})
+
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, o.Sysctls)
+	assert.Equal(t, []Ulimit{{Name: "nofile", Soft: 2, Hard: 2}}, o.Ulimits)
+	assert.Equal(t, int64(64), o.ShmSize)
+	// The original options must not be modified.
+	assert.Equal(t, "1", egg.Sysctls["b"])
+}
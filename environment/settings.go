@@ -5,7 +5,9 @@ import (
 	"math"
 	"strconv"
 
+	"emperror.dev/errors"
 	"github.com/apex/log"
+	"github.com/docker/docker/api/types/blkiodev"
 	"github.com/docker/docker/api/types/container"
 
 	"github.com/pterodactyl/wings/config"
@@ -56,6 +58,19 @@ type Limits struct {
 	// Sets which CPU threads can be used by the docker instance.
 	Threads string `json:"threads"`
 
+	// The maximum rate in megabits per second that the server can receive and send
+	// data over the network. A value of 0 means there is no limit.
+	NetworkIngress int64 `json:"network_ingress"`
+	NetworkEgress  int64 `json:"network_egress"`
+
+	// The maximum rate in megabytes per second and operations per second that the
+	// server can read from and write to the disk its data is stored on. These are
+	// absolute limits, unlike IoWeight. A value of 0 means there is no limit.
+	IoReadLimit  int64  `json:"io_read_limit"`
+	IoWriteLimit int64  `json:"io_write_limit"`
+	IoReadIops   uint64 `json:"io_read_iops"`
+	IoWriteIops  uint64 `json:"io_write_iops"`
+
 	OOMDisabled bool `json:"oom_disabled"`
 }
 
@@ -105,10 +120,23 @@ func (l Limits) ProcessLimit() int64 {
 	return config.Get().Docker.ContainerPidLimit
 }
 
-func (l Limits) AsContainerResources() container.Resources {
+// HasIoLimits returns true if any of the absolute disk IO limits are set.
+func (l Limits) HasIoLimits() bool {
+	return l.IoReadLimit > 0 || l.IoWriteLimit > 0 || l.IoReadIops > 0 || l.IoWriteIops > 0
+}
+
+// AsContainerResources returns the resource limits in the format used by Docker.
+// Disk IO limits are applied to the block device that the server data directory
+// is stored on, and an error is returned if they are set but that device cannot
+// be determined.
+//
+// Docker ignores the disk IO limits when updating a running container, so they
+// are only applied by this when the container is created. Use the cgroup of the
+// container to change them while it is running.
+func (l Limits) AsContainerResources() (container.Resources, error) {
 	pids := l.ProcessLimit()
 
-	return container.Resources{
+	r := container.Resources{
 		Memory:            l.BoundedMemoryLimit(),
 		MemoryReservation: l.MemoryLimit * 1_000_000,
 		MemorySwap:        l.ConvertedSwap(),
@@ -120,6 +148,25 @@ func (l Limits) AsContainerResources() container.Resources {
 		CpusetCpus:        l.Threads,
 		PidsLimit:         &pids,
 	}
+
+	if l.HasIoLimits() {
+		dev, err := DataDevice()
+		if err != nil {
+			return r, errors.WithStackIf(err)
+		}
+		throttle := func(rate uint64) []*blkiodev.ThrottleDevice {
+			if rate == 0 {
+				return nil
+			}
+			return []*blkiodev.ThrottleDevice{{Path: dev, Rate: rate}}
+		}
+		r.BlkioDeviceReadBps = throttle(uint64(l.IoReadLimit) * 1_000_000)
+		r.BlkioDeviceWriteBps = throttle(uint64(l.IoWriteLimit) * 1_000_000)
+		r.BlkioDeviceReadIOps = throttle(l.IoReadIops)
+		r.BlkioDeviceWriteIOps = throttle(l.IoWriteIops)
+	}
+
+	return r, nil
 }
 
 type Variables map[string]interface{}
@@ -25,9 +25,32 @@ type Stats struct {
 
 	// Current network transmit in & out for a container.
 	Network NetworkStats `json:"network"`
+
+	// Current disk reads and writes for a container.
+	Io IoStats `json:"io"`
+}
+
+type IoStats struct {
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+	ReadOps    uint64 `json:"read_ops"`
+	WriteOps   uint64 `json:"write_ops"`
+
+	// The current disk throughput of the container in bytes per second.
+	ReadRate  uint64 `json:"read_rate"`
+	WriteRate uint64 `json:"write_rate"`
+
+	// Set when the container is reading or writing at close to one of the absolute
+	// IO limits for the server, meaning it is likely being throttled.
+	Throttled bool `json:"throttled"`
 }
 
 type NetworkStats struct {
 	RxBytes uint64 `json:"rx_bytes"`
 	TxBytes uint64 `json:"tx_bytes"`
+
+	// The current throughput of the container in bytes per second, calculated from
+	// the change in the totals since the previous reading.
+	RxRate uint64 `json:"rx_rate"`
+	TxRate uint64 `json:"tx_rate"`
 }
@@ -0,0 +1,121 @@
+// Package metrics exposes telemetry for the daemon and the servers running on
+// it in the Prometheus exposition format.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+const namespace = "wings"
+
+var registry = prometheus.NewRegistry()
+
+var factory = promauto.With(registry)
+
+var (
+	// HTTPRequestDuration tracks the time taken to respond to API requests, the
+	// route is the matched route pattern rather than the raw path so that server
+	// and file identifiers do not create unbounded label values.
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to respond to HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// SftpSessions is the number of SFTP sessions currently open.
+	SftpSessions = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sftp",
+		Name:      "sessions",
+		Help:      "Number of SFTP sessions currently open.",
+	})
+
+	// BackupDuration tracks the time taken to generate backups.
+	BackupDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "backup",
+		Name:      "duration_seconds",
+		Help:      "Time taken to generate server backups.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+	}, []string{"adapter", "successful"})
+
+	// TransferDuration tracks the time taken for each side of a server transfer
+	// to complete, either archiving the server on the source node or downloading
+	// and extracting it on the target node.
+	TransferDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "transfer",
+		Name:      "duration_seconds",
+		Help:      "Time taken to archive or receive server transfers.",
+		Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200},
+	}, []string{"side", "successful"})
+
+	// PanelRequestErrors counts the requests to the Panel that failed, either with
+	// an error response or without receiving a response at all. Every attempt is
+	// counted, including those that are retried.
+	PanelRequestErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "panel",
+		Name:      "request_errors_total",
+		Help:      "Number of failed requests made to the Panel.",
+	}, []string{"status"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{Namespace: namespace}),
+	)
+}
+
+// MustRegister adds additional collectors to the registry, panicking if any of
+// them cannot be registered.
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+// ObserveDuration records the time elapsed since start on the given histogram.
+func ObserveDuration(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+// Handler returns the HTTP handler that serves the metrics, requiring the
+// configured bearer token if one is set.
+func Handler() http.Handler {
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := config.Get().Api.Metrics.Token
+		if token != "" {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "The required authorization heads were not present in the request.", http.StatusUnauthorized)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Serve starts the metrics webserver using the address in the configuration.
+// This blocks until the server is stopped.
+func Serve() error {
+	cfg := config.Get().Api.Metrics
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	addr := cfg.Host + ":" + strconv.Itoa(cfg.Port)
+	log.WithField("address", addr).Info("metrics webserver is now listening")
+	return http.ListenAndServe(addr, mux)
+}
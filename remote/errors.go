@@ -2,6 +2,7 @@ package remote
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 
 	"emperror.dev/errors"
@@ -41,6 +42,21 @@ func AsRequestError(err error) *RequestError {
 	return nil
 }
 
+// IsPanelUnavailable returns true if the error is because the Panel could not be
+// reached or failed to handle the request, rather than it rejecting the request.
+// Only network errors and 5xx responses count, any other error such as failing
+// to decode a response means the Panel was reached.
+func IsPanelUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if rerr := AsRequestError(err); rerr != nil {
+		return rerr.StatusCode() >= 500
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr)
+}
+
 // Error returns the error response in a string form that can be more easily
 // consumed.
 func (re *RequestError) Error() string {
@@ -0,0 +1,42 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPanelUnavailable(t *testing.T) {
+	requestError := func(code int) error {
+		return errors.WithStack(&RequestError{response: &http.Response{StatusCode: code}})
+	}
+
+	// A request to a closed port fails with a network error.
+	c, s := createTestClient(func(rw http.ResponseWriter, r *http.Request) {})
+	s.Close()
+	_, err := c.requestOnce(context.Background(), http.MethodGet, "/test", nil)
+	require.Error(t, err)
+
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{"no error", nil, false},
+		{"network error", err, true},
+		{"wrapped network error", errors.WrapIf(err, "http: request creation failed"), true},
+		{"server error", requestError(http.StatusBadGateway), true},
+		{"not found", requestError(http.StatusNotFound), false},
+		{"unauthorized", requestError(http.StatusUnauthorized), false},
+		{"decode error", errors.WithStack(json.Unmarshal([]byte("{"), &struct{}{})), false},
+		{"other error", errors.New("something went wrong"), false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.unavailable, IsPanelUnavailable(tc.err), tc.name)
+	}
+}
@@ -10,12 +10,14 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
 	"github.com/cenkalti/backoff/v4"
 
+	"github.com/pterodactyl/wings/metrics"
 	"github.com/pterodactyl/wings/system"
 )
 
@@ -28,17 +30,33 @@ type Client interface {
 	SetArchiveStatus(ctx context.Context, uuid string, successful bool) error
 	SetBackupStatus(ctx context.Context, backup string, data BackupRequest) error
 	SendRestorationStatus(ctx context.Context, backup string, successful bool) error
-	SetInstallationStatus(ctx context.Context, uuid string, successful bool) error
+	SendScheduleStatus(ctx context.Context, uuid string, schedule string, data ScheduleStatusRequest) error
+	SetInstallationStatus(ctx context.Context, uuid string, data InstallStatusRequest) error
 	SetTransferStatus(ctx context.Context, uuid string, successful bool) error
 	ValidateSftpCredentials(ctx context.Context, request SftpAuthRequest) (SftpAuthResponse, error)
+	SetCredentials(id, token string)
+	Outbox() ([]OutboxEntry, error)
+	StartOutbox(ctx context.Context)
 }
 
 type client struct {
 	httpClient  *http.Client
 	baseUrl     string
-	tokenId     string
-	token       string
 	maxAttempts int
+
+	// Guards the credentials so that they can be swapped when the node token is
+	// rotated without restarting Wings.
+	mu      sync.RWMutex
+	tokenId string
+	token   string
+
+	// Status callbacks that could not be delivered to the Panel, nil if callbacks
+	// are not persisted.
+	outbox  *outbox
+	flushMu sync.Mutex
+
+	// The key used to sign requests, empty if requests are not signed.
+	signingKey string
 }
 
 // New returns a new HTTP request client that is used for making authenticated
@@ -66,6 +84,14 @@ func WithCredentials(id, token string) ClientOption {
 	}
 }
 
+// SetCredentials replaces the credentials used for requests made to the Panel.
+func (c *client) SetCredentials(id, token string) {
+	c.mu.Lock()
+	c.tokenId = id
+	c.token = token
+	c.mu.Unlock()
+}
+
 // WithHttpClient sets the underlying HTTP client instance to use when making
 // requests to the Panel API.
 func WithHttpClient(httpClient *http.Client) ClientOption {
@@ -103,19 +129,36 @@ func (c *client) requestOnce(ctx context.Context, method, path string, body io.R
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", fmt.Sprintf("Pterodactyl Wings/v%s (id:%s)", system.Version, c.tokenId))
+	c.mu.RLock()
+	id, token := c.tokenId, c.token
+	c.mu.RUnlock()
+
+	req.Header.Set("User-Agent", fmt.Sprintf("Pterodactyl Wings/v%s (id:%s)", system.Version, id))
 	req.Header.Set("Accept", "application/vnd.pterodactyl.v1+json")
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s.%s", c.tokenId, c.token))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s.%s", id, token))
 
 	// Call all opts functions to allow modifying the request
 	for _, o := range opts {
 		o(req)
 	}
 
+	// The request is signed last so that the signature covers any changes made
+	// to it by the functions above.
+	if c.signingKey != "" {
+		if err := c.sign(req); err != nil {
+			return nil, err
+		}
+	}
+
 	debugLogRequest(req)
 
 	res, err := c.httpClient.Do(req)
+	if err != nil {
+		metrics.PanelRequestErrors.WithLabelValues("none").Inc()
+	} else if res.StatusCode >= 400 {
+		metrics.PanelRequestErrors.WithLabelValues(strconv.Itoa(res.StatusCode)).Inc()
+	}
 	return &Response{res}, err
 }
 
@@ -2,6 +2,7 @@ package remote
 
 import (
 	"context"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -93,3 +94,28 @@ func TestPost(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
 }
+
+func TestRequestSigning(t *testing.T) {
+	var nonces []string
+	c, _ := createTestClient(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, `{"hello":"world"}`, string(b))
+
+		ts := r.Header.Get(TimestampHeader)
+		nonce := r.Header.Get(NonceHeader)
+		assert.NotEmpty(t, ts)
+		assert.Len(t, nonce, 32)
+		assert.Equal(t, Signature("secret", r.Method, "/test?a=b", ts, nonce, b), r.Header.Get(SignatureHeader))
+		assert.NotEqual(t, Signature("other", r.Method, "/test?a=b", ts, nonce, b), r.Header.Get(SignatureHeader))
+		nonces = append(nonces, nonce)
+	})
+	WithSigningKey("secret")(c)
+
+	for i := 0; i < 2; i++ {
+		r, err := c.Post(context.Background(), "/test?a=b", map[string]string{"hello": "world"})
+		assert.NoError(t, err)
+		assert.NotNil(t, r)
+	}
+	assert.Len(t, nonces, 2)
+	assert.NotEqual(t, nonces[0], nonces[1])
+}
@@ -0,0 +1,320 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/google/uuid"
+
+	"github.com/pterodactyl/wings/system"
+)
+
+// OutboxEntry is a status callback that could not be delivered to the Panel and
+// is waiting to be sent again. The ID is sent as the Idempotency-Key header on
+// every attempt so that the Panel can ignore a callback it has already handled.
+type OutboxEntry struct {
+	ID        string          `json:"id"`
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Body      json.RawMessage `json:"body,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	Attempts  int             `json:"attempts"`
+	// The number of attempts that the Panel responded to with an error, rather
+	// than not being reachable at all.
+	Failures  int    `json:"failures"`
+	LastError string `json:"last_error,omitempty"`
+	// Dead is set once the callback has failed too many times or is too old to
+	// be delivered. It is kept in the outbox so that it can be inspected, but is
+	// no longer sent and does not hold up the callbacks after it.
+	Dead bool `json:"dead,omitempty"`
+}
+
+const (
+	// The maximum number of callbacks kept in the outbox. Once it is full new
+	// callbacks are not queued and the error is returned to the caller instead.
+	outboxMaxEntries = 500
+	// The number of times the Panel can respond to a callback with an error before
+	// it is given up on, so that a callback the Panel can never handle does not
+	// stop the ones after it from being delivered.
+	outboxMaxFailures = 20
+	// The amount of time after which a callback is given up on.
+	outboxMaxAge = time.Hour * 24
+)
+
+// apply sets the body and idempotency key for the callback on a request. The
+// body is set here rather than when the request is created so that every retry
+// of the request is sent with the full body.
+func (e *OutboxEntry) apply(r *http.Request) {
+	r.Header.Set("Idempotency-Key", e.ID)
+	if len(e.Body) > 0 {
+		r.Body = ioutil.NopCloser(bytes.NewReader(e.Body))
+		r.ContentLength = int64(len(e.Body))
+	}
+}
+
+// outbox is the list of callbacks waiting to be delivered to the Panel, which
+// is persisted to the disk so that callbacks survive Wings being restarted.
+type outbox struct {
+	mu      sync.Mutex
+	path    string
+	loaded  bool
+	entries []OutboxEntry
+}
+
+func (o *outbox) load() error {
+	if o.loaded {
+		return nil
+	}
+	b, err := ioutil.ReadFile(o.path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "remote: could not read outbox")
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &o.entries); err != nil {
+			return errors.Wrap(err, "remote: could not parse outbox")
+		}
+	}
+	o.loaded = true
+	return nil
+}
+
+func (o *outbox) save() error {
+	b, err := json.Marshal(o.entries)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(o.path+".tmp", b, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(o.path+".tmp", o.path))
+}
+
+// list returns a copy of the callbacks waiting in the outbox, oldest first.
+func (o *outbox) list() ([]OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	return append([]OutboxEntry{}, o.entries...), nil
+}
+
+// pending returns the number of callbacks in the outbox that are still waiting
+// to be delivered.
+func (o *outbox) pending() (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.load(); err != nil {
+		return 0, err
+	}
+	var n int
+	for _, e := range o.entries {
+		if !e.Dead {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// push adds a callback to the end of the outbox. If the outbox is full the
+// oldest callback that was given up on is removed to make space, otherwise an
+// error is returned. The whole outbox is written out on every change, which is
+// why the number of entries is limited.
+func (o *outbox) push(e OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.load(); err != nil {
+		return err
+	}
+	if len(o.entries) >= outboxMaxEntries {
+		i := 0
+		for i < len(o.entries) && !o.entries[i].Dead {
+			i++
+		}
+		if i == len(o.entries) {
+			return errors.New("remote: outbox is full")
+		}
+		o.entries = append(o.entries[:i], o.entries[i+1:]...)
+	}
+	o.entries = append(o.entries, e)
+	return o.save()
+}
+
+// update replaces the callback with the same ID, or removes it from the outbox
+// if remove is true.
+func (o *outbox) update(e OutboxEntry, remove bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, v := range o.entries {
+		if v.ID != e.ID {
+			continue
+		}
+		if remove {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+		} else {
+			o.entries[i] = e
+		}
+		return o.save()
+	}
+	return nil
+}
+
+// WithOutbox enables the outbox for status callbacks, persisting callbacks that
+// could not be delivered to the file at the given path.
+func WithOutbox(path string) ClientOption {
+	return func(c *client) {
+		c.outbox = &outbox{path: path}
+	}
+}
+
+// Outbox returns the status callbacks that are waiting to be delivered to the
+// Panel, oldest first.
+func (c *client) Outbox() ([]OutboxEntry, error) {
+	if c.outbox == nil {
+		return []OutboxEntry{}, nil
+	}
+	return c.outbox.list()
+}
+
+// StartOutbox begins delivering the callbacks waiting in the outbox to the Panel
+// in the order they were created, retrying every 15 seconds until the context
+// is canceled.
+func (c *client) StartOutbox(ctx context.Context) {
+	if c.outbox == nil {
+		return
+	}
+	go c.flushOutbox(ctx)
+	system.Every(ctx, time.Second*15, func(_ time.Time) {
+		c.flushOutbox(ctx)
+	})
+}
+
+// send delivers a status callback to the Panel. If the Panel cannot be reached
+// the callback is added to the outbox rather than being lost, and nil is
+// returned since it will be delivered once the Panel is reachable again.
+//
+// The outbox is not flushed while this runs so that a callback cannot reach the
+// Panel ahead of one that was queued before it.
+func (c *client) send(ctx context.Context, method, path string, data interface{}) error {
+	e := OutboxEntry{ID: uuid.New().String(), Method: method, Path: path, CreatedAt: time.Now()}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		e.Body = b
+	}
+	if c.outbox == nil {
+		return c.deliver(ctx, &e)
+	}
+
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	// Callbacks have to reach the Panel in the order they happened, so if there
+	// are any still waiting this one has to wait behind them.
+	pending, err := c.outbox.pending()
+	if err != nil {
+		return err
+	}
+	if pending == 0 {
+		err := c.deliver(ctx, &e)
+		if err == nil || !retryable(ctx, err) {
+			return err
+		}
+		e.Attempts = 1
+		e.LastError = err.Error()
+		if IsRequestError(err) {
+			e.Failures = 1
+		}
+	}
+	log.WithFields(log.Fields{"method": method, "path": path, "id": e.ID}).Warn("remote: panel is unreachable, queued status callback for later delivery")
+	return c.outbox.push(e)
+}
+
+func (c *client) deliver(ctx context.Context, e *OutboxEntry) error {
+	res, err := c.request(ctx, e.Method, e.Path, nil, e.apply)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	return nil
+}
+
+// flushOutbox sends the callbacks in the outbox to the Panel in order, stopping
+// at the first one that cannot be delivered so that the order is preserved. A
+// callback that has failed too many times or is too old is given up on and the
+// ones after it are sent.
+func (c *client) flushOutbox(ctx context.Context) {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	entries, err := c.outbox.list()
+	if err != nil {
+		log.WithField("error", err).Error("remote: failed to read outbox")
+		return
+	}
+	for _, e := range entries {
+		e := e
+		if e.Dead {
+			continue
+		}
+		res, err := c.requestOnce(ctx, e.Method, e.Path, nil, e.apply)
+		if err == nil {
+			err = res.Error()
+			_ = res.Body.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		l := log.WithFields(log.Fields{"method": e.Method, "path": e.Path, "id": e.ID})
+		if err != nil && retryable(ctx, err) {
+			e.Attempts++
+			e.LastError = err.Error()
+			if IsRequestError(err) {
+				e.Failures++
+			}
+			e.Dead = e.Failures >= outboxMaxFailures || time.Since(e.CreatedAt) > outboxMaxAge
+			if err := c.outbox.update(e, false); err != nil {
+				log.WithField("error", err).Warn("remote: failed to update outbox")
+				return
+			}
+			if !e.Dead {
+				return
+			}
+			l.WithField("error", err).Error("remote: giving up on delivering queued status callback to panel")
+			continue
+		}
+		if err != nil {
+			l.WithField("error", err).Warn("remote: panel rejected queued status callback, removing it from the outbox")
+		} else {
+			l.Info("remote: delivered queued status callback to panel")
+		}
+		if err := c.outbox.update(e, true); err != nil {
+			log.WithField("error", err).Warn("remote: failed to update outbox")
+			return
+		}
+	}
+}
+
+// retryable returns true if a callback that failed with the error should be
+// attempted again later. Anything other than the Panel rejecting the request is
+// assumed to be because it is unreachable.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if rerr := AsRequestError(err); rerr != nil {
+		s := rerr.StatusCode()
+		return s >= 500 || s == http.StatusTooManyRequests
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package remote
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxQueuesUndeliveredCallbacks(t *testing.T) {
+	var keys []string
+	var bodies []string
+	up := false
+	c, _ := createTestClient(func(rw http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if !up {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	c.maxAttempts = 1
+	p := filepath.Join(t.TempDir(), "outbox.json")
+	WithOutbox(p)(c)
+
+	err := c.SetInstallationStatus(context.Background(), "uuid", InstallStatusRequest{Successful: true})
+	require.NoError(t, err)
+	err = c.SendRestorationStatus(context.Background(), "backup", true)
+	require.NoError(t, err)
+
+	// The second callback is queued behind the first without being sent.
+	assert.Len(t, keys, 2)
+	entries, err := c.Outbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/servers/uuid/install", entries[0].Path)
+	assert.Equal(t, 1, entries[0].Attempts)
+	assert.Equal(t, "/backups/backup/restore", entries[1].Path)
+	assert.Equal(t, 0, entries[1].Attempts)
+
+	// The outbox is read back from the disk by a new client.
+	c2 := &client{httpClient: c.httpClient, baseUrl: c.baseUrl}
+	WithOutbox(p)(c2)
+
+	up = true
+	c2.flushOutbox(context.Background())
+
+	entries, err = c2.Outbox()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	require.Len(t, keys, 4)
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+	assert.NotEqual(t, keys[0], keys[3])
+	assert.Equal(t, bodies[0], bodies[2])
+	assert.JSONEq(t, `{"successful":true}`, bodies[3])
+}
+
+func TestOutboxDropsRejectedCallbacks(t *testing.T) {
+	c, _ := createTestClient(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	})
+	WithOutbox(filepath.Join(t.TempDir(), "outbox.json"))(c)
+
+	err := c.SetArchiveStatus(context.Background(), "uuid", true)
+	assert.Error(t, err)
+	entries, err := c.Outbox()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOutboxGivesUpOnFailingCallbacks(t *testing.T) {
+	var delivered []string
+	c, _ := createTestClient(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/servers/poison/install" {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		delivered = append(delivered, r.URL.Path)
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	WithOutbox(filepath.Join(t.TempDir(), "outbox.json"))(c)
+	require.NoError(t, c.outbox.push(OutboxEntry{ID: "a", Method: http.MethodPost, Path: "/servers/poison/install", CreatedAt: time.Now()}))
+	require.NoError(t, c.outbox.push(OutboxEntry{ID: "b", Method: http.MethodPost, Path: "/servers/uuid/install", CreatedAt: time.Now()}))
+
+	// The callback behind the failing one waits until it is given up on.
+	for i := 0; i < outboxMaxFailures-1; i++ {
+		c.flushOutbox(context.Background())
+	}
+	assert.Empty(t, delivered)
+
+	c.flushOutbox(context.Background())
+	assert.Equal(t, []string{"/servers/uuid/install"}, delivered)
+	entries, err := c.Outbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "a", entries[0].ID)
+	assert.True(t, entries[0].Dead)
+	assert.Equal(t, outboxMaxFailures, entries[0].Failures)
+
+	// A callback that has given up on does not hold up new ones.
+	require.NoError(t, c.SetArchiveStatus(context.Background(), "uuid", true))
+	assert.Len(t, delivered, 2)
+}
+
+func TestOutboxGivesUpOnOldCallbacks(t *testing.T) {
+	c, _ := createTestClient(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+	WithOutbox(filepath.Join(t.TempDir(), "outbox.json"))(c)
+	require.NoError(t, c.outbox.push(OutboxEntry{ID: "a", Method: http.MethodPost, Path: "/servers/uuid/install", CreatedAt: time.Now().Add(-outboxMaxAge - time.Minute)}))
+	require.NoError(t, c.outbox.push(OutboxEntry{ID: "b", Method: http.MethodPost, Path: "/servers/uuid/install", CreatedAt: time.Now()}))
+
+	c.flushOutbox(context.Background())
+	entries, err := c.Outbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.True(t, entries[0].Dead)
+	assert.False(t, entries[1].Dead)
+	assert.Equal(t, 1, entries[1].Attempts)
+}
+
+func TestOutboxIsLimited(t *testing.T) {
+	o := &outbox{path: filepath.Join(t.TempDir(), "outbox.json")}
+	for i := 0; i < outboxMaxEntries; i++ {
+		require.NoError(t, o.push(OutboxEntry{ID: strconv.Itoa(i)}))
+	}
+	assert.Error(t, o.push(OutboxEntry{ID: "full"}))
+
+	// Callbacks that were given up on make way for new ones.
+	e := o.entries[1]
+	e.Dead = true
+	require.NoError(t, o.update(e, false))
+	require.NoError(t, o.push(OutboxEntry{ID: "new"}))
+	n, err := o.pending()
+	require.NoError(t, err)
+	assert.Equal(t, outboxMaxEntries, n)
+	assert.Equal(t, "2", o.entries[1].ID)
+	assert.Equal(t, "new", o.entries[outboxMaxEntries-1].ID)
+}
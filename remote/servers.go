@@ -3,6 +3,7 @@ package remote
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 	"sync"
 
@@ -74,8 +75,15 @@ func (c *client) GetServerConfiguration(ctx context.Context, uuid string) (Serve
 	}
 	defer res.Body.Close()
 
-	err = res.BindJSON(&config)
-	return config, err
+	if err := res.BindJSON(&config); err != nil {
+		return config, err
+	}
+	var raw RawServerData
+	if err := res.BindJSON(&raw); err != nil {
+		return config, err
+	}
+	config.RawProcessConfiguration = raw.ProcessConfiguration
+	return config, nil
 }
 
 func (c *client) GetInstallationScript(ctx context.Context, uuid string) (InstallationScript, error) {
@@ -90,22 +98,12 @@ func (c *client) GetInstallationScript(ctx context.Context, uuid string) (Instal
 	return config, err
 }
 
-func (c *client) SetInstallationStatus(ctx context.Context, uuid string, successful bool) error {
-	resp, err := c.Post(ctx, fmt.Sprintf("/servers/%s/install", uuid), d{"successful": successful})
-	if err != nil {
-		return err
-	}
-	_ = resp.Body.Close()
-	return nil
+func (c *client) SetInstallationStatus(ctx context.Context, uuid string, data InstallStatusRequest) error {
+	return c.send(ctx, http.MethodPost, fmt.Sprintf("/servers/%s/install", uuid), data)
 }
 
 func (c *client) SetArchiveStatus(ctx context.Context, uuid string, successful bool) error {
-	resp, err := c.Post(ctx, fmt.Sprintf("/servers/%s/archive", uuid), d{"successful": successful})
-	if err != nil {
-		return err
-	}
-	_ = resp.Body.Close()
-	return nil
+	return c.send(ctx, http.MethodPost, fmt.Sprintf("/servers/%s/archive", uuid), d{"successful": successful})
 }
 
 func (c *client) SetTransferStatus(ctx context.Context, uuid string, successful bool) error {
@@ -113,12 +111,7 @@ func (c *client) SetTransferStatus(ctx context.Context, uuid string, successful
 	if successful {
 		state = "success"
 	}
-	resp, err := c.Get(ctx, fmt.Sprintf("/servers/%s/transfer/%s", uuid, state), nil)
-	if err != nil {
-		return err
-	}
-	_ = resp.Body.Close()
-	return nil
+	return c.send(ctx, http.MethodGet, fmt.Sprintf("/servers/%s/transfer/%s", uuid, state), nil)
 }
 
 // ValidateSftpCredentials makes a request to determine if the username and
@@ -158,24 +151,21 @@ func (c *client) GetBackupRemoteUploadURLs(ctx context.Context, backup string, s
 }
 
 func (c *client) SetBackupStatus(ctx context.Context, backup string, data BackupRequest) error {
-	resp, err := c.Post(ctx, fmt.Sprintf("/backups/%s", backup), data)
-	if err != nil {
-		return err
-	}
-	_ = resp.Body.Close()
-	return nil
+	return c.send(ctx, http.MethodPost, fmt.Sprintf("/backups/%s", backup), data)
 }
 
 // SendRestorationStatus triggers a request to the Panel to notify it that a
 // restoration has been completed and the server should be marked as being
 // activated again.
 func (c *client) SendRestorationStatus(ctx context.Context, backup string, successful bool) error {
-	resp, err := c.Post(ctx, fmt.Sprintf("/backups/%s/restore", backup), d{"successful": successful})
-	if err != nil {
-		return err
-	}
-	_ = resp.Body.Close()
-	return nil
+	return c.send(ctx, http.MethodPost, fmt.Sprintf("/backups/%s/restore", backup), d{"successful": successful})
+}
+
+// SendScheduleStatus reports the result of a schedule that was executed locally
+// by Wings back to the Panel so that the last run time and any task failures
+// can be displayed to the user.
+func (c *client) SendScheduleStatus(ctx context.Context, uuid string, schedule string, data ScheduleStatusRequest) error {
+	return c.send(ctx, http.MethodPost, fmt.Sprintf("/servers/%s/schedules/%s", uuid, schedule), data)
 }
 
 // getServersPaged returns a subset of servers from the Panel API using the
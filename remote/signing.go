@@ -0,0 +1,66 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// Headers sent with every request to the Panel when request signing is enabled.
+const (
+	SignatureHeader = "X-Pterodactyl-Signature"
+	TimestampHeader = "X-Pterodactyl-Timestamp"
+	NonceHeader     = "X-Pterodactyl-Nonce"
+)
+
+// WithSigningKey signs every request made to the Panel with the given key. The
+// key is separate from the authentication token so that a leaked token alone
+// cannot be used to make requests on behalf of the node.
+func WithSigningKey(key string) ClientOption {
+	return func(c *client) {
+		c.signingKey = key
+	}
+}
+
+// Signature returns the HMAC-SHA256 signature for a request. The signature
+// covers the method, the path and query, the timestamp and nonce sent with the
+// request, and a hash of the body, each separated by a newline.
+func Signature(key, method, uri, timestamp, nonce string, body []byte) string {
+	sum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(method + "\n" + uri + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(sum[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign adds the signature headers to a request. The body of the request is read
+// in order to hash it and then replaced so that it can still be sent.
+func (c *client) sign(r *http.Request) error {
+	var body []byte
+	if r.Body != nil {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return errors.Wrap(err, "remote: could not read request body to sign")
+		}
+		_ = r.Body.Close()
+		body = b
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+	n := make([]byte, 16)
+	if _, err := rand.Read(n); err != nil {
+		return errors.Wrap(err, "remote: could not generate request nonce")
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := hex.EncodeToString(n)
+	r.Header.Set(TimestampHeader, ts)
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set(SignatureHeader, Signature(c.signingKey, r.Method, r.URL.RequestURI(), ts, nonce, body))
+	return nil
+}
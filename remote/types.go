@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/apex/log"
 
@@ -44,6 +45,10 @@ type Pagination struct {
 type ServerConfigurationResponse struct {
 	Settings             json.RawMessage       `json:"settings"`
 	ProcessConfiguration *ProcessConfiguration `json:"process_configuration"`
+
+	// The process configuration exactly as it was returned by the Panel, which is
+	// what gets cached to the disk since the parsed form cannot be marshaled back.
+	RawProcessConfiguration json.RawMessage `json:"-"`
 }
 
 // InstallationScript defines installation script information for a server
@@ -153,3 +158,39 @@ type BackupRequest struct {
 	Size         int64  `json:"size"`
 	Successful   bool   `json:"successful"`
 }
+
+// InstallStatusRequest is sent to the Panel once the installation process for a
+// server has finished.
+type InstallStatusRequest struct {
+	Successful bool `json:"successful"`
+	// Reinstall is true if the process was run against an existing server.
+	Reinstall bool `json:"reinstall"`
+	// RolledBack is true if the installation failed and the files for the server
+	// were restored to how they were before the process started.
+	RolledBack bool `json:"rolled_back"`
+	// Canceled is true if the process was stopped by a request to cancel it.
+	Canceled bool   `json:"canceled"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ScheduleStatusRequest is sent to the Panel once a locally executed schedule
+// has finished running all of its tasks.
+type ScheduleStatusRequest struct {
+	Successful bool                 `json:"successful"`
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt time.Time            `json:"finished_at"`
+	Tasks      []ScheduleTaskStatus `json:"tasks"`
+}
+
+// ScheduleTaskStatus is the result of a single task that ran as part of a
+// schedule. If the task created a backup the details of that backup are
+// included so the Panel is able to track it.
+type ScheduleTaskStatus struct {
+	Action     string         `json:"action"`
+	Successful bool           `json:"successful"`
+	Error      string         `json:"error,omitempty"`
+	Backup     *BackupRequest `json:"backup,omitempty"`
+	BackupUuid string         `json:"backup_uuid,omitempty"`
+	// The adapter the backup was stored with.
+	BackupAdapter string `json:"backup_adapter,omitempty"`
+}
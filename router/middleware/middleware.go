@@ -6,7 +6,9 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
@@ -14,6 +16,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/metrics"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/server"
 	"github.com/pterodactyl/wings/server/filesystem"
@@ -161,6 +164,21 @@ func AttachRequestID() gin.HandlerFunc {
 	}
 }
 
+// RecordMetrics records the time taken to respond to each request. Requests are
+// grouped by the matched route pattern rather than the full path, which includes
+// server and file identifiers that would create unbounded label values.
+func RecordMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
+
 // AttachServerManager attaches the server manager to the request context which
 // allows routes to access the underlying server collection.
 func AttachServerManager(m *server.Manager) gin.HandlerFunc {
@@ -218,10 +236,12 @@ func CaptureErrors() gin.HandlerFunc {
 // SetAccessControlHeaders sets the access request control headers on all of
 // the requests.
 func SetAccessControlHeaders() gin.HandlerFunc {
-	origins := config.Get().AllowedOrigins
-	location := config.Get().PanelLocation
-
 	return func(c *gin.Context) {
+		// The origins are read on every request so that they can be changed when
+		// the configuration is reloaded.
+		cfg := config.Get()
+		origins := cfg.AllowedOrigins
+		location := cfg.PanelLocation
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PATCH, PUT, DELETE, OPTIONS")
 		// Maximum age allowable under Chromium v76 is 2 hours, so just use that since
@@ -285,7 +305,15 @@ func RequireAuthorization() gin.HandlerFunc {
 		// We don't put this value outside this function since the node's authentication
 		// token can be changed on the fly and the config.Get() call returns a copy, so
 		// if it is rotated this value will never properly get updated.
-		token := config.Get().AuthenticationToken
+		tokens := config.AuthenticationTokens()
+
+		// When a client CA is configured the Panel must also present a certificate
+		// signed by it, so that the token alone is not enough to control the node.
+		if config.Get().Api.Ssl.ClientCA != "" && (c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A valid client certificate is required to access this endpoint."})
+			return
+		}
+
 		auth := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
 		if len(auth) != 2 || auth[0] != "Bearer" {
 			c.Header("WWW-Authenticate", "Bearer")
@@ -296,8 +324,27 @@ func RequireAuthorization() gin.HandlerFunc {
 		// All requests to Wings must be authorized with the authentication token present in
 		// the Wings configuration file. Remeber, all requests to Wings come from the Panel
 		// backend, or using a signed JWT for temporary authentication.
-		if subtle.ConstantTimeCompare([]byte(auth[1]), []byte(token)) != 1 {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not authorized to access this endpoint."})
+		//
+		// After the token is rotated the previous token is also accepted until the
+		// grace period for it ends.
+		for i, token := range tokens {
+			if subtle.ConstantTimeCompare([]byte(auth[1]), []byte(token)) == 1 {
+				c.Set("current_token", i == 0)
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not authorized to access this endpoint."})
+	}
+}
+
+// RequireCurrentToken rejects requests authorized with the previous token of the
+// node while it is still within its grace period. This must be used after the
+// RequireAuthorization middleware.
+func RequireCurrentToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("current_token") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint can only be accessed using the current node token."})
 			return
 		}
 		c.Next()
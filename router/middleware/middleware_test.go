@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/metrics"
+)
+
+func TestRequireAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.Set(&config.Configuration{
+		AuthenticationToken:         "current",
+		PreviousAuthenticationToken: "previous",
+		PreviousTokenExpiresAt:      time.Now().Add(time.Minute),
+	})
+
+	r := gin.New()
+	r.Use(RequireAuthorization())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.POST("/rotate", RequireCurrentToken(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	do := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/", ""))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/", "wrong"))
+	assert.Equal(t, http.StatusNoContent, do(http.MethodGet, "/", "current"))
+	assert.Equal(t, http.StatusNoContent, do(http.MethodGet, "/", "previous"))
+
+	// The previous token cannot be used to rotate the token again.
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, "/rotate", "current"))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/rotate", "previous"))
+
+	config.Update(func(c *config.Configuration) {
+		c.PreviousTokenExpiresAt = time.Now().Add(-time.Second)
+	})
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/", "previous"))
+}
+
+func TestRequireAuthorizationClientCertificate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.Set(&config.Configuration{AuthenticationToken: "current"})
+	config.Update(func(c *config.Configuration) {
+		c.Api.Ssl.ClientCA = "/etc/pterodactyl/ca.pem"
+	})
+
+	r := gin.New()
+	r.Use(RequireAuthorization())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	do := func(state *tls.ConnectionState) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer current")
+		req.TLS = state
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	cert := &x509.Certificate{}
+	// A valid token is not enough without a connection over TLS.
+	assert.Equal(t, http.StatusUnauthorized, do(nil))
+	// A certificate that was not verified against the client CA is rejected.
+	assert.Equal(t, http.StatusUnauthorized, do(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}))
+	assert.Equal(t, http.StatusNoContent, do(&tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}))
+}
+
+func TestRecordMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(RecordMetrics(), gin.Recovery())
+	r.GET("/api/servers/:server", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/panic", func(c *gin.Context) { panic("test") })
+
+	count := func(method, route, status string) uint64 {
+		var m dto.Metric
+		o := metrics.HTTPRequestDuration.WithLabelValues(method, route, status)
+		require.NoError(t, o.(prometheus.Metric).Write(&m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	do := func(method, path string) {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+	}
+
+	before := count(http.MethodGet, "/api/servers/:server", "204")
+	do(http.MethodGet, "/api/servers/abc")
+	do(http.MethodGet, "/api/servers/def")
+	// Requests are grouped by the route pattern rather than the path.
+	assert.Equal(t, before+2, count(http.MethodGet, "/api/servers/:server", "204"))
+
+	before = count(http.MethodGet, "unmatched", "404")
+	do(http.MethodGet, "/missing")
+	assert.Equal(t, before+1, count(http.MethodGet, "unmatched", "404"))
+
+	// Requests that panic are recorded with the status set when recovering.
+	before = count(http.MethodGet, "/panic", "500")
+	do(http.MethodGet, "/panic")
+	assert.Equal(t, before+1, count(http.MethodGet, "/panic", "500"))
+}
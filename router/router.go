@@ -14,6 +14,9 @@ func Configure(m *server.Manager, client remote.Client) *gin.Engine {
 	gin.SetMode("release")
 
 	router := gin.New()
+	// Metrics are recorded outside of the recovery middleware so that requests which
+	// panic are still counted with the status code they were aborted with.
+	router.Use(middleware.RecordMetrics())
 	router.Use(gin.Recovery())
 	router.Use(middleware.AttachRequestID(), middleware.CaptureErrors(), middleware.SetAccessControlHeaders())
 	router.Use(middleware.AttachServerManager(m), middleware.AttachApiClient(client))
@@ -53,6 +56,9 @@ func Configure(m *server.Manager, client remote.Client) *gin.Engine {
 	protected := router.Use(middleware.RequireAuthorization())
 	protected.POST("/api/update", postUpdateConfiguration)
 	protected.GET("/api/system", getSystemInformation)
+	protected.GET("/api/system/allocations", getSystemAllocations)
+	protected.GET("/api/system/outbox", getSystemOutbox)
+	protected.POST("/api/system/rotate-token", middleware.RequireCurrentToken(), postSystemRotateToken)
 	protected.GET("/api/servers", getAllServers)
 	protected.POST("/api/servers", postCreateServer)
 	protected.POST("/api/transfer", postTransfer)
@@ -67,9 +73,12 @@ func Configure(m *server.Manager, client remote.Client) *gin.Engine {
 		server.DELETE("", deleteServer)
 
 		server.GET("/logs", getServerLogs)
+		server.GET("/logs/history", getServerLogHistory)
+		server.GET("/stats/history", getServerStatsHistory)
 		server.POST("/power", postServerPower)
 		server.POST("/commands", postServerCommands)
 		server.POST("/install", postServerInstall)
+		server.DELETE("/install", deleteServerInstall)
 		server.POST("/reinstall", postServerReinstall)
 		server.POST("/ws/deny", postServerDenyWSTokens)
 
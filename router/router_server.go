@@ -5,7 +5,9 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"regexp"
 	"strconv"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
@@ -15,6 +17,8 @@ import (
 	"github.com/pterodactyl/wings/router/middleware"
 	"github.com/pterodactyl/wings/router/tokens"
 	"github.com/pterodactyl/wings/server"
+	"github.com/pterodactyl/wings/server/consolelog"
+	"github.com/pterodactyl/wings/server/history"
 )
 
 // Returns a single server from the collection of servers.
@@ -42,6 +46,111 @@ func getServerLogs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": out})
 }
 
+// Returns the persisted console history for a server. Results are returned newest
+// first and can be filtered by a time range and a regular expression. To fetch the
+// next page of results pass the returned "next" value as the "cursor" parameter.
+func getServerLogHistory(c *gin.Context) {
+	s := ExtractServer(c)
+
+	cl := s.ConsoleLog()
+	if cl == nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "Console log persistence is not enabled on this instance.",
+		})
+		return
+	}
+
+	var q consolelog.Query
+	var err error
+	if q.Since, err = parseHistoryTime(c.Query("since")); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The \"since\" parameter is not a valid timestamp."})
+		return
+	}
+	if q.Until, err = parseHistoryTime(c.Query("until")); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The \"until\" parameter is not a valid timestamp."})
+		return
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		if q.Before, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The \"cursor\" parameter is not valid."})
+			return
+		}
+	}
+	if search := c.Query("search"); search != "" {
+		if q.Search, err = regexp.Compile(search); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The \"search\" parameter is not a valid regular expression."})
+			return
+		}
+	}
+	q.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if q.Limit <= 0 || q.Limit > 1000 {
+		q.Limit = 1000
+	}
+
+	out, err := cl.Search(q)
+	if err != nil {
+		NewServerError(err, s).Abort(c)
+		return
+	}
+
+	var next *uint64
+	if len(out) == q.Limit {
+		next = &out[len(out)-1].Sequence
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out, "next": next})
+}
+
+// Returns the resource usage history for a server between the given times. Ranges
+// starting within the last hour are returned at a per-second resolution, older
+// ranges are returned per-minute.
+func getServerStatsHistory(c *gin.Context) {
+	s := ExtractServer(c)
+
+	h := s.StatsHistory()
+	if h == nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "Resource usage history is not available for this server.",
+		})
+		return
+	}
+
+	since, err := parseHistoryTime(c.Query("since"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The \"since\" parameter is not a valid timestamp."})
+		return
+	}
+	until, err := parseHistoryTime(c.Query("until"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The \"until\" parameter is not a valid timestamp."})
+		return
+	}
+	if !until.IsZero() && until.Before(since) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The \"until\" parameter must not be before the \"since\" parameter."})
+		return
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-time.Hour)
+	}
+
+	out, res := h.Query(since, until)
+	if out == nil {
+		out = []history.Point{}
+	}
+	c.JSON(http.StatusOK, gin.H{"resolution": int(res.Seconds()), "data": out})
+}
+
+// parseHistoryTime parses a timestamp passed to the console history endpoint,
+// which may be either a unix timestamp in seconds or an RFC3339 string.
+func parseHistoryTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
+
 // Handles a request to control the power state of a server. If the action being passed
 // through is invalid a 404 is returned. Otherwise, a HTTP/202 Accepted response is returned
 // and the actual power action is run asynchronously so that we don't have to block the
@@ -138,6 +247,10 @@ func patchServer(c *gin.Context) {
 	buf.ReadFrom(c.Request.Body)
 
 	if err := s.UpdateDataStructure(buf.Bytes()); err != nil {
+		if errors.Is(err, server.ErrAllocationConflict) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		NewServerError(err, s).Abort(c)
 		return
 	}
@@ -160,6 +273,26 @@ func postServerInstall(c *gin.Context) {
 	c.Status(http.StatusAccepted)
 }
 
+// Cancels the installation process that is currently running for a server. The
+// container is stopped in the background and the Panel is notified once the
+// process has finished cleaning up.
+func deleteServerInstall(c *gin.Context) {
+	s := ExtractServer(c)
+
+	if err := s.CancelInstall(); err != nil {
+		if errors.Is(err, server.ErrNotInstalling) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error": "There is no installation process running for this server.",
+			})
+			return
+		}
+		NewServerError(err, s).Abort(c)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
 // Reinstalls a server.
 func postServerReinstall(c *gin.Context) {
 	s := ExtractServer(c)
@@ -220,6 +353,22 @@ func deleteServer(c *gin.Context) {
 		}
 	}(s.Filesystem().Path())
 
+	// Remove the persisted console history for the server as well. The log itself is
+	// closed once the server context is canceled above.
+	if cl := s.ConsoleLog(); cl != nil {
+		go func(p string) {
+			if err := os.RemoveAll(p); err != nil {
+				log.WithFields(log.Fields{"path": p, "error": err}).Warn("failed to remove server console logs during deletion process")
+			}
+		}(cl.Path())
+	}
+
+	if h := s.StatsHistory(); h != nil {
+		if err := h.Remove(); err != nil {
+			log.WithFields(log.Fields{"path": h.Path(), "error": err}).Warn("failed to remove server resource usage history during deletion process")
+		}
+	}
+
 	middleware.ExtractManager(c).Remove(func(server *server.Server) bool {
 		return server.ID() == s.ID()
 	})
@@ -5,7 +5,9 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/gin-gonic/gin"
@@ -14,6 +16,7 @@ import (
 	"github.com/pterodactyl/wings/installer"
 	"github.com/pterodactyl/wings/router/middleware"
 	"github.com/pterodactyl/wings/server"
+	"github.com/pterodactyl/wings/server/installcache"
 	"github.com/pterodactyl/wings/system"
 )
 
@@ -26,7 +29,67 @@ func getSystemInformation(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, i)
+	c.JSON(http.StatusOK, struct {
+		*system.Information
+		InstallCache installcache.Stats `json:"install_cache"`
+	}{i, installcache.GetStats()})
+}
+
+// Returns every port assigned to the servers on this instance along with any
+// processes on the host that are already listening on them.
+func getSystemAllocations(c *gin.Context) {
+	r, err := middleware.ExtractManager(c).AllocationReport()
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// Returns the status callbacks that are waiting to be delivered to the Panel
+// because it could not be reached when they were sent.
+func getSystemOutbox(c *gin.Context) {
+	entries, err := middleware.ExtractApiClient(c).Outbox()
+	if err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// Replaces the node token with a new one. The current token remains valid for
+// the grace period so that the Panel and any JWTs it has already issued keep
+// working while it switches over to the new token.
+func postSystemRotateToken(c *gin.Context) {
+	var data struct {
+		TokenId     string `json:"token_id"`
+		Token       string `json:"token"`
+		GracePeriod *int   `json:"grace_period"`
+	}
+	if err := c.BindJSON(&data); err != nil {
+		return
+	}
+	if len(data.Token) < 32 {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "The new token must be at least 32 characters long."})
+		return
+	}
+	grace := time.Minute * 5
+	if data.GracePeriod != nil {
+		max := int(config.MaxTokenGracePeriod.Seconds())
+		if *data.GracePeriod < 0 || *data.GracePeriod > max {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "The grace period must be between 0 and " + strconv.Itoa(max) + " seconds."})
+			return
+		}
+		grace = time.Duration(*data.GracePeriod) * time.Second
+	}
+	if err := config.RotateToken(data.TokenId, data.Token, grace); err != nil {
+		middleware.CaptureAndAbort(c, err)
+		return
+	}
+	cfg := config.Get()
+	middleware.ExtractApiClient(c).SetCredentials(cfg.AuthenticationTokenId, cfg.AuthenticationToken)
+	log.WithFields(log.Fields{"token_id": cfg.AuthenticationTokenId, "grace_period": grace.String()}).Info("node token has been rotated")
+	c.Status(http.StatusNoContent)
 }
 
 // Returns all of the servers that are registered and configured correctly on
@@ -55,6 +118,10 @@ func postCreateServer(c *gin.Context) {
 			})
 			return
 		}
+		if errors.Is(err, server.ErrAllocationConflict) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 
 		middleware.CaptureAndAbort(c, err)
 		return
@@ -113,14 +180,25 @@ func postUpdateConfiguration(c *gin.Context) {
 		cfg.Api.Ssl.KeyFile = strings.ToLower(config.Get().Api.Ssl.KeyFile)
 		cfg.Api.Ssl.CertificateFile = strings.ToLower(config.Get().Api.Ssl.CertificateFile)
 	}
+	// Never write a configuration to the disk that Wings would not be able to boot
+	// with the next time it is started.
+	if err := cfg.Validate(); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 	// Try to write this new configuration to the disk before updating our global
 	// state with it.
 	if err := config.WriteToDisk(cfg); err != nil {
 		WithError(c, err)
 		return
 	}
-	// Since we wrote it to the disk successfully now update the global configuration
-	// state to use this new configuration struct.
-	config.Set(cfg)
-	c.Status(http.StatusNoContent)
+	// Since we wrote it to the disk successfully now apply the fields that can be
+	// changed live, the rest will be used once Wings is restarted.
+	res, err := config.Apply(cfg)
+	if err != nil {
+		WithError(c, err)
+		return
+	}
+	middleware.ExtractManager(c).ApplyConfiguration()
+	c.JSON(http.StatusOK, res)
 }
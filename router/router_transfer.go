@@ -26,6 +26,7 @@ import (
 
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/installer"
+	"github.com/pterodactyl/wings/metrics"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/router/middleware"
 	"github.com/pterodactyl/wings/router/tokens"
@@ -147,8 +148,10 @@ func postServerArchive(c *gin.Context) {
 		s.Events().Publish(server.TransferStatusEvent, "starting")
 		sendTransferLog("Attempting to archive server...")
 
+		start := time.Now()
 		hasError := true
 		defer func() {
+			metrics.ObserveDuration(metrics.TransferDuration.WithLabelValues("source", strconv.FormatBool(!hasError)), start)
 			if !hasError {
 				return
 			}
@@ -332,7 +335,11 @@ func postTransfer(c *gin.Context) {
 
 	data.log().Info("handling incoming server transfer request")
 	go func(data *serverTransferRequest) {
+		start := time.Now()
 		hasError := true
+		defer func() {
+			metrics.ObserveDuration(metrics.TransferDuration.WithLabelValues("target", strconv.FormatBool(!hasError)), start)
+		}()
 
 		// Create a new server installer. This will only configure the environment and not
 		// run the installer scripts.
@@ -3,6 +3,7 @@ package tokens
 import (
 	"time"
 
+	"emperror.dev/errors"
 	"github.com/gbrlsnchs/jwt/v3"
 
 	"github.com/pterodactyl/wings/config"
@@ -25,5 +26,13 @@ func ParseToken(token []byte, data TokenData) error {
 
 	_, err := jwt.Verify(token, config.GetJwtAlgorithm(), &data, verifyOptions)
 
+	// Tokens signed before the node token was rotated are still accepted until the
+	// grace period for the previous token ends.
+	if errors.Is(err, jwt.ErrHMACVerification) {
+		if prev := config.GetPreviousJwtAlgorithm(); prev != nil {
+			_, err = jwt.Verify(token, prev, &data, verifyOptions)
+		}
+	}
+
 	return err
 }
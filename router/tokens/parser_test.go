@@ -0,0 +1,43 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func signToken(t *testing.T, key string) []byte {
+	p := UploadPayload{
+		Payload:  jwt.Payload{ExpirationTime: jwt.NumericDate(time.Now().Add(time.Minute))},
+		UniqueId: "unique",
+	}
+	token, err := jwt.Sign(p, jwt.NewHS256([]byte(key)))
+	require.NoError(t, err)
+	return token
+}
+
+func TestParseTokenAcceptsPreviousTokenDuringGracePeriod(t *testing.T) {
+	config.Set(&config.Configuration{
+		AuthenticationToken:         "current",
+		PreviousAuthenticationToken: "previous",
+		PreviousTokenExpiresAt:      time.Now().Add(time.Minute),
+	})
+
+	var p UploadPayload
+	assert.NoError(t, ParseToken(signToken(t, "current"), &p))
+	assert.NoError(t, ParseToken(signToken(t, "previous"), &p))
+	assert.Equal(t, "unique", p.UniqueId)
+	assert.Error(t, ParseToken(signToken(t, "other"), &p))
+
+	config.Set(&config.Configuration{
+		AuthenticationToken:         "current",
+		PreviousAuthenticationToken: "previous",
+		PreviousTokenExpiresAt:      time.Now().Add(-time.Second),
+	})
+	assert.Error(t, ParseToken(signToken(t, "previous"), &p))
+}
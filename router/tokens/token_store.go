@@ -1,42 +1,279 @@
 package tokens
 
 import (
+	"context"
+	"encoding/binary"
 	"sync"
 	"time"
 
+	"emperror.dev/errors"
+	"github.com/apex/log"
 	"github.com/patrickmn/go-cache"
+	"go.etcd.io/bbolt"
+
+	"github.com/pterodactyl/wings/system"
+)
+
+var (
+	tokensBucket   = []byte("tokens")
+	denylistBucket = []byte("denylist")
+	metaBucket     = []byte("meta")
+	shutdownKey    = []byte("shutdown")
+)
+
+const (
+	// The amount of time a one-time token ID is remembered for after it is used.
+	tokenTTL = time.Minute * 60
+	// The amount of time a denied JTI is remembered for. Websocket tokens are only
+	// valid for a few minutes, so any token issued before the JTI was denied will
+	// have long since expired by this point.
+	denylistTTL = time.Hour * 24
 )
 
 type TokenStore struct {
 	sync.Mutex
-	cache *cache.Cache
+	cache  *cache.Cache
+	denied *cache.Cache
+
+	// The database the tokens are persisted to. If this is nil the tokens are
+	// only kept in memory and are lost when Wings is restarted.
+	db *bbolt.DB
+
+	// The time at which Wings was last shut down cleanly, as recorded in the
+	// database. This is zero if it is not known.
+	shutdownAt time.Time
 }
 
-var _tokens *TokenStore
+var (
+	_tokensMu sync.Mutex
+	_tokens   *TokenStore
+)
 
 // Returns the global unique token store cache. This is used to validate
 // one time token usage by storing any received tokens in a local memory
 // cache until they are ready to expire.
 func getTokenStore() *TokenStore {
+	_tokensMu.Lock()
+	defer _tokensMu.Unlock()
 	if _tokens == nil {
 		_tokens = &TokenStore{
-			cache: cache.New(time.Minute*60, time.Minute*5),
+			cache:  cache.New(tokenTTL, time.Minute*5),
+			denied: cache.New(denylistTTL, time.Minute*5),
 		}
 	}
 
 	return _tokens
 }
 
+func setTokenStore(t *TokenStore) {
+	_tokensMu.Lock()
+	_tokens = t
+	_tokensMu.Unlock()
+}
+
+// OpenTokenStore persists used one-time tokens and denied websocket JTIs to
+// the database at the given path so that they survive Wings being restarted.
+// Expired entries are removed from the database every five minutes until the
+// context is canceled, at which point the shutdown time is recorded and the
+// database is closed.
+func OpenTokenStore(ctx context.Context, path string) error {
+	t, err := openTokenStore(path)
+	if err != nil {
+		return err
+	}
+	setTokenStore(t)
+
+	system.Every(ctx, time.Minute*5, func(_ time.Time) {
+		t.prune()
+	})
+	go func() {
+		<-ctx.Done()
+		t.close()
+	}()
+	return nil
+}
+
+func openTokenStore(path string) (*TokenStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second * 5})
+	if err != nil {
+		return nil, errors.Wrap(err, "tokens: could not open token store")
+	}
+	t := &TokenStore{db: db}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, b := range [][]byte{tokensBucket, denylistBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		// The shutdown time is removed once it has been read so that it is not used
+		// again if Wings does not shut down cleanly this time around.
+		m := tx.Bucket(metaBucket)
+		if v := m.Get(shutdownKey); v != nil {
+			t.shutdownAt = decodeTime(v)
+		}
+		return m.Delete(shutdownKey)
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "tokens: could not create token store buckets")
+	}
+	t.prune()
+	return t, nil
+}
+
+// close records the time at which Wings was shut down and closes the database.
+func (t *TokenStore) close() {
+	t.Lock()
+	defer t.Unlock()
+	err := t.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(shutdownKey, encodeTime(time.Now()))
+	})
+	if err != nil {
+		log.WithField("error", err).Warn("tokens: failed to record shutdown time in token store")
+	}
+	if err := t.db.Close(); err != nil {
+		log.WithField("error", err).Warn("tokens: failed to close token store")
+	}
+}
+
 // Checks if a token is valid or not.
 func (t *TokenStore) IsValidToken(token string) bool {
 	t.Lock()
 	defer t.Unlock()
 
-	_, exists := t.cache.Get(token)
+	if t.db == nil {
+		_, exists := t.cache.Get(token)
+
+		if !exists {
+			t.cache.Add(token, "", tokenTTL)
+		}
 
-	if !exists {
-		t.cache.Add(token, "", time.Minute*60)
+		return !exists
+	}
+
+	var exists bool
+	err := t.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(tokensBucket)
+		if v := b.Get([]byte(token)); v != nil && time.Now().Before(decodeTime(v)) {
+			exists = true
+			return nil
+		}
+		return b.Put([]byte(token), encodeTime(time.Now().Add(tokenTTL)))
+	})
+	// If the token could not be recorded as being used it cannot be allowed
+	// through, otherwise it could be used again.
+	if err != nil {
+		log.WithField("error", err).Error("tokens: failed to record token in token store")
+		return false
 	}
 
 	return !exists
 }
+
+// deny marks all tokens with the given JTI issued before the time as denied.
+func (t *TokenStore) deny(jti string, at time.Time) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.db == nil {
+		t.denied.Set(jti, at, denylistTTL)
+		return
+	}
+
+	err := t.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(denylistBucket).Put([]byte(jti), encodeTime(at))
+	})
+	if err != nil {
+		log.WithFields(log.Fields{"jti": jti, "error": err}).Error("tokens: failed to add JTI to denylist")
+	}
+}
+
+// deniedAt returns the time at which the JTI was denied, if it has been. If the
+// denylist cannot be read the JTI is treated as having been denied just now.
+func (t *TokenStore) deniedAt(jti string) (time.Time, bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.db == nil {
+		if v, ok := t.denied.Get(jti); ok {
+			return v.(time.Time), true
+		}
+		return time.Time{}, false
+	}
+
+	var at time.Time
+	var ok bool
+	err := t.db.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(denylistBucket).Get([]byte(jti)); v != nil {
+			at, ok = decodeTime(v), true
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithFields(log.Fields{"jti": jti, "error": err}).Error("tokens: failed to read JTI denylist")
+		return time.Now(), true
+	}
+	return at, ok
+}
+
+// issuedWhileDown returns true if a token issued at the given time could have
+// been denied while Wings was not running to record it, which is the case for
+// any token issued after the last shutdown but before the given boot time. If
+// the shutdown time is not known every token issued before boot is included.
+func (t *TokenStore) issuedWhileDown(iat time.Time, boot time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+	if !iat.Before(boot) {
+		return false
+	}
+	return t.shutdownAt.IsZero() || !iat.Before(t.shutdownAt)
+}
+
+// prune removes the expired tokens and denied JTIs from the database.
+func (t *TokenStore) prune() {
+	t.Lock()
+	defer t.Unlock()
+
+	now := time.Now()
+	err := t.db.Update(func(tx *bbolt.Tx) error {
+		if err := pruneBucket(tx.Bucket(tokensBucket), func(v time.Time) bool { return now.After(v) }); err != nil {
+			return err
+		}
+		return pruneBucket(tx.Bucket(denylistBucket), func(v time.Time) bool { return now.After(v.Add(denylistTTL)) })
+	})
+	if err != nil {
+		log.WithField("error", err).Warn("tokens: failed to prune expired entries from token store")
+	}
+}
+
+func pruneBucket(b *bbolt.Bucket, expired func(v time.Time) bool) error {
+	var keys [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		if expired(decodeTime(v)) {
+			keys = append(keys, append([]byte{}, k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+func decodeTime(b []byte) time.Time {
+	if len(b) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
@@ -0,0 +1,131 @@
+package tokens
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T, path string) *TokenStore {
+	s, err := openTokenStore(path)
+	require.NoError(t, err)
+	return s
+}
+
+func putEntry(t *testing.T, s *TokenStore, bucket []byte, key string, v time.Time) {
+	require.NoError(t, s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), encodeTime(v))
+	}))
+}
+
+func hasEntry(t *testing.T, s *TokenStore, bucket []byte, key string) bool {
+	var ok bool
+	require.NoError(t, s.db.View(func(tx *bbolt.Tx) error {
+		ok = tx.Bucket(bucket).Get([]byte(key)) != nil
+		return nil
+	}))
+	return ok
+}
+
+func TestTokenStoreIsValidToken(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "tokens.db"))
+	defer s.close()
+
+	assert.True(t, s.IsValidToken("abc"))
+	assert.False(t, s.IsValidToken("abc"))
+
+	// Once the entry for a token has expired it is no longer remembered.
+	putEntry(t, s, tokensBucket, "expired", time.Now().Add(-time.Second))
+	assert.True(t, s.IsValidToken("expired"))
+	assert.False(t, s.IsValidToken("expired"))
+}
+
+func TestTokenStorePrune(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "tokens.db"))
+	defer s.close()
+
+	putEntry(t, s, tokensBucket, "expired", time.Now().Add(-time.Second))
+	putEntry(t, s, tokensBucket, "valid", time.Now().Add(time.Minute))
+	putEntry(t, s, denylistBucket, "old", time.Now().Add(-denylistTTL-time.Second))
+	putEntry(t, s, denylistBucket, "recent", time.Now())
+
+	s.prune()
+	assert.False(t, hasEntry(t, s, tokensBucket, "expired"))
+	assert.True(t, hasEntry(t, s, tokensBucket, "valid"))
+	assert.False(t, hasEntry(t, s, denylistBucket, "old"))
+	assert.True(t, hasEntry(t, s, denylistBucket, "recent"))
+}
+
+func TestTokenStorePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.db")
+	s := openTestStore(t, path)
+	assert.True(t, s.IsValidToken("abc"))
+	denied := time.Now()
+	s.deny("jti", denied)
+	s.close()
+
+	s = openTestStore(t, path)
+	defer s.close()
+	assert.False(t, s.IsValidToken("abc"))
+	at, ok := s.deniedAt("jti")
+	require.True(t, ok)
+	assert.True(t, at.Equal(denied))
+
+	// The clean shutdown is recorded so only tokens issued after it are treated as
+	// issued while Wings was down.
+	require.False(t, s.shutdownAt.IsZero())
+	boot := s.shutdownAt.Add(time.Minute)
+	assert.False(t, s.issuedWhileDown(s.shutdownAt.Add(-time.Second), boot))
+	assert.True(t, s.issuedWhileDown(s.shutdownAt.Add(time.Millisecond), boot))
+	assert.False(t, s.issuedWhileDown(boot, boot))
+}
+
+func TestTokenStoreWithoutShutdownTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.db")
+	s := openTestStore(t, path)
+	defer s.close()
+
+	// Without a recorded shutdown, such as after a crash, every token issued before
+	// boot is rejected.
+	assert.True(t, s.shutdownAt.IsZero())
+	boot := time.Now()
+	assert.True(t, s.issuedWhileDown(boot.Add(-time.Hour), boot))
+}
+
+func TestTokenStoreFailsClosed(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "tokens.db"))
+	require.NoError(t, s.db.Close())
+
+	assert.False(t, s.IsValidToken("abc"))
+	_, ok := s.deniedAt("jti")
+	assert.True(t, ok)
+}
+
+func TestWebsocketPayloadDenylisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.db")
+	s := openTestStore(t, path)
+	s.close()
+	s = openTestStore(t, path)
+	defer s.close()
+
+	orig := getTokenStore()
+	setTokenStore(s)
+	defer setTokenStore(orig)
+
+	p := &WebsocketPayload{Payload: jwt.Payload{JWTID: "jti", IssuedAt: jwt.NumericDate(time.Now())}}
+	assert.False(t, p.Denylisted())
+
+	DenyJTI("jti")
+	assert.True(t, p.Denylisted())
+
+	p.IssuedAt = jwt.NumericDate(time.Now().Add(time.Second))
+	assert.False(t, p.Denylisted())
+
+	p.IssuedAt = nil
+	assert.True(t, p.Denylisted())
+}
@@ -10,29 +10,25 @@ import (
 	"github.com/gbrlsnchs/jwt/v3"
 )
 
-// The time at which Wings was booted. No JWT's created before this time are allowed to
-// connect to the socket since they may have been marked as denied already and therefore
-// could be invalid at this point.
+// The time at which Wings was booted. No JWT's created between the last shutdown and this
+// time are allowed to connect to the socket since they may have been marked as denied while
+// Wings was not running and therefore could be invalid at this point. If the denylist is
+// only kept in memory this applies to every JWT created before this time.
 //
 // By doing this we make it so that a user who gets disconnected from Wings due to a Wings
 // reboot just needs to request a new token as if their old token had expired naturally.
 var wingsBootTime = time.Now()
 
-// A map that contains any JTI's that have been denied by the Panel and the time at which
-// they were marked as denied. Therefore any JWT with the same JTI and an IssuedTime that
-// is the same as or before this time should be considered invalid.
+// Adds a JTI to the denylist by marking any JWTs generated before the current time as
+// being invalid if they use the same JTI.
 //
 // This is used to allow the Panel to revoke tokens en-masse for a given user & server
 // combination since the JTI for tokens is just MD5(user.id + server.uuid). When a server
 // is booted this listing is fetched from the panel and the Websocket is dynamically updated.
-var denylist sync.Map
-
-// Adds a JTI to the denylist by marking any JWTs generated before the current time as
-// being invalid if they use the same JTI.
 func DenyJTI(jti string) {
 	log.WithField("jti", jti).Debugf("adding \"%s\" to JTI denylist", jti)
 
-	denylist.Store(jti, time.Now())
+	getTokenStore().deny(jti, time.Now())
 }
 
 // A JWT payload for Websocket connections. This JWT is passed along to the Websocket after
@@ -72,16 +68,16 @@ func (p *WebsocketPayload) Denylisted() bool {
 		return true
 	}
 
-	// If the time that the token was issued is before the time at which Wings was booted
-	// then the token is invalid for our purposes, even if the token "has permission".
-	if p.IssuedAt.Time.Before(wingsBootTime) {
+	// If the token was issued while Wings was not running then the token is invalid for
+	// our purposes, even if the token "has permission".
+	if getTokenStore().issuedWhileDown(p.IssuedAt.Time, wingsBootTime) {
 		return true
 	}
 
 	// Finally, if the token was issued before a time that is currently denied for this
 	// token instance, ignore the permissions response.
-	if t, ok := denylist.Load(p.JWTID); ok {
-		if p.IssuedAt.Time.Before(t.(time.Time)) {
+	if t, ok := getTokenStore().deniedAt(p.JWTID); ok {
+		if p.IssuedAt.Time.Before(t) {
 			return true
 		}
 	}
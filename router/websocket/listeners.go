@@ -44,11 +44,13 @@ var e = []string{
 	server.InstallOutputEvent,
 	server.InstallStartedEvent,
 	server.InstallCompletedEvent,
+	server.InstallProgressEvent,
 	server.DaemonMessageEvent,
 	server.BackupCompletedEvent,
 	server.BackupRestoreCompletedEvent,
 	server.TransferLogsEvent,
 	server.TransferStatusEvent,
+	server.TriggerEvent,
 }
 
 // Listens for different events happening on a server and sends them along
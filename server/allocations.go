@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+
+	"emperror.dev/errors"
+	"github.com/buger/jsonparser"
+
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/system"
+)
+
+// ErrAllocationConflict is returned when a server is assigned a port that is
+// already assigned to another server on the node.
+var ErrAllocationConflict = errors.Sentinel("server: allocation is already assigned to another server")
+
+// allocationIndex tracks the ports assigned to every server on the node so that
+// two servers are never configured to bind the same port.
+type allocationIndex struct {
+	mu    sync.RWMutex
+	ports map[string][]environment.Port
+}
+
+func newAllocationIndex() *allocationIndex {
+	return &allocationIndex{ports: make(map[string][]environment.Port)}
+}
+
+// reserve replaces the ports for the server in the index with the allocations
+// given, as long as they are valid and none of them overlap with the ports of
+// another server. The returned function puts back the ports the server had
+// reserved before this call.
+func (i *allocationIndex) reserve(id string, a environment.Allocations) (func(), error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	ports := a.Ports()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for other, used := range i.ports {
+		if other == id {
+			continue
+		}
+		for _, p := range ports {
+			for _, u := range used {
+				if p.Overlaps(u) {
+					return nil, errors.WithDetails(errors.Wrap(ErrAllocationConflict, p.String()+" overlaps with "+u.String()+" assigned to server "+other), "server", other)
+				}
+			}
+		}
+	}
+	prev, ok := i.ports[id]
+	i.ports[id] = ports
+	return func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		if ok {
+			i.ports[id] = prev
+		} else {
+			delete(i.ports, id)
+		}
+	}, nil
+}
+
+// snapshot returns a copy of the ports assigned to every server.
+func (i *allocationIndex) snapshot() map[string][]environment.Port {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	out := make(map[string][]environment.Port, len(i.ports))
+	for id, ports := range i.ports {
+		out[id] = append([]environment.Port{}, ports...)
+	}
+	return out
+}
+
+// release removes the ports for a server from the index.
+func (i *allocationIndex) release(id string) {
+	i.mu.Lock()
+	delete(i.ports, id)
+	i.mu.Unlock()
+}
+
+// checkAllocations reserves the allocations in the configuration payload for
+// the server. If the payload does not contain any allocations the existing ones
+// are left untouched. The returned function undoes the reservation and must be
+// called if the configuration is not updated after all.
+func (s *Server) checkAllocations(uuid string, data []byte) (func(), error) {
+	if s.allocations == nil {
+		return func() {}, nil
+	}
+	b, _, _, err := jsonparser.Get(data, "allocations")
+	if err != nil {
+		return func() {}, nil
+	}
+	var a environment.Allocations
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, errors.Wrap(err, "server: could not unmarshal allocations")
+	}
+	// Mappings and ranges are only replaced when they are sent, so merge in the
+	// existing values the same way the configuration update does.
+	if len(a.Mappings) == 0 {
+		a.Mappings = s.Config().Allocations.Mappings
+	}
+	if _, _, _, err := jsonparser.Get(b, "ranges"); err != nil {
+		a.Ranges = s.Config().Allocations.Ranges
+	}
+	id := s.ID()
+	if id == "" {
+		id = uuid
+	}
+	return s.allocations.reserve(id, a)
+}
+
+// AllocationReport describes a port that is assigned to a server and whether a
+// process on the host is already listening on it.
+type AllocationReport struct {
+	environment.Port
+	Server string `json:"server"`
+	State  string `json:"state"`
+	// The processes on the host listening on the port. While the server is running
+	// this will generally be the Docker proxy for the port.
+	Listeners []system.Listener `json:"listeners"`
+	// Conflict is true if something other than the server is listening on the
+	// port, which will prevent the server from starting.
+	Conflict bool `json:"conflict"`
+}
+
+// AllocationReport returns every port assigned to the servers on the node along
+// with any host processes that are listening on them.
+func (m *Manager) AllocationReport() ([]AllocationReport, error) {
+	listeners, err := system.Listeners()
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the index rather than holding its lock while looking up servers, since
+	// the manager lock is always taken before the index lock.
+	out := make([]AllocationReport, 0)
+	for id, ports := range m.allocations.snapshot() {
+		state := ""
+		if s, ok := m.Get(id); ok {
+			state = s.Environment.State()
+		}
+		for _, p := range ports {
+			r := AllocationReport{Port: p, Server: id, State: state, Listeners: []system.Listener{}}
+			for _, l := range listeners {
+				if !p.Overlaps(environment.Port{Ip: l.Ip, Port: l.Port, Protocol: l.Protocol}) {
+					continue
+				}
+				r.Listeners = append(r.Listeners, l)
+				// Wings itself listens on the ports of hibernating servers.
+				if l.Command != "docker-proxy" && l.Pid != os.Getpid() {
+					r.Conflict = true
+				}
+			}
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Server != out[j].Server {
+			return out[i].Server < out[j].Server
+		}
+		return out[i].Port.String() < out[j].Port.String()
+	})
+	return out, nil
+}
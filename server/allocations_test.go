@@ -0,0 +1,32 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+func TestAllocationIndexRollback(t *testing.T) {
+	i := newAllocationIndex()
+	a := environment.Allocations{Mappings: map[string][]int{"127.0.0.1": {25565}}}
+	_, err := i.reserve("one", a)
+	require.NoError(t, err)
+
+	_, err = i.reserve("two", a)
+	assert.ErrorIs(t, err, ErrAllocationConflict)
+
+	// Rolling back a changed reservation restores the previous ports.
+	rollback, err := i.reserve("one", environment.Allocations{Mappings: map[string][]int{"127.0.0.1": {25566}}})
+	require.NoError(t, err)
+	rollback()
+	assert.Equal(t, 25565, i.snapshot()["one"][0].Port)
+
+	// Rolling back a new reservation removes it entirely.
+	rollback, err = i.reserve("three", environment.Allocations{Mappings: map[string][]int{"127.0.0.1": {25567}}})
+	require.NoError(t, err)
+	rollback()
+	assert.NotContains(t, i.snapshot(), "three")
+}
@@ -5,12 +5,15 @@ import (
 	"io/fs"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
 	"github.com/docker/docker/client"
 
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/metrics"
 	"github.com/pterodactyl/wings/remote"
 	"github.com/pterodactyl/wings/server/backup"
 )
@@ -68,7 +71,9 @@ func (s *Server) Backup(b backup.BackupInterface) error {
 		}
 	}
 
+	start := time.Now()
 	ad, err := b.Generate(s.Context(), s.Filesystem().Path(), ignored)
+	metrics.ObserveDuration(metrics.BackupDuration.WithLabelValues(string(b.Adapter()), strconv.FormatBool(err == nil)), start)
 	if err != nil {
 		if err := s.notifyPanelOfBackup(b.Identifier(), &backup.ArchiveDetails{}, false); err != nil {
 			s.Log().WithFields(log.Fields{
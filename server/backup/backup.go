@@ -35,6 +35,8 @@ type BackupInterface interface {
 	// Identifier returns the UUID of this backup as tracked by the panel
 	// instance.
 	Identifier() string
+	// Adapter returns the type of adapter used to store this backup.
+	Adapter() AdapterType
 	// WithLogContext attaches additional context to the log output for this
 	// backup.
 	WithLogContext(map[string]interface{})
@@ -83,6 +85,10 @@ func (b *Backup) Identifier() string {
 	return b.Uuid
 }
 
+func (b *Backup) Adapter() AdapterType {
+	return b.adapter
+}
+
 // Path returns the path for this specific backup.
 func (b *Backup) Path() string {
 	return path.Join(config.Get().System.BackupDirectory, b.Identifier()+".tar.gz")
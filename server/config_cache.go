@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/remote"
+)
+
+// cacheConfiguration writes the configuration for a server returned by the Panel
+// to the disk so that the server can still be booted if the Panel is not
+// reachable the next time Wings starts.
+func cacheConfiguration(data remote.RawServerData) error {
+	if !config.Get().RemoteQuery.CacheConfigurations || data.Uuid == "" || len(data.ProcessConfiguration) == 0 {
+		return nil
+	}
+	dir := config.Get().System.GetServerConfigCachePath()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrap(err, "server: could not create configuration cache directory")
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	p := filepath.Join(dir, data.Uuid+".json")
+	if err := ioutil.WriteFile(p+".tmp", b, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(p+".tmp", p))
+}
+
+// cacheConfigurations replaces the cached configurations with the servers that
+// were returned by the Panel, removing any that no longer exist on it.
+func cacheConfigurations(servers []remote.RawServerData) {
+	keep := make(map[string]bool, len(servers))
+	for _, data := range servers {
+		keep[data.Uuid+".json"] = true
+		if err := cacheConfiguration(data); err != nil {
+			log.WithFields(log.Fields{"server": data.Uuid, "error": err}).Warn("failed to cache server configuration")
+		}
+	}
+	files, err := ioutil.ReadDir(config.Get().System.GetServerConfigCachePath())
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if !keep[f.Name()] && strings.HasSuffix(f.Name(), ".json") {
+			_ = os.Remove(filepath.Join(config.Get().System.GetServerConfigCachePath(), f.Name()))
+		}
+	}
+}
+
+// readCachedConfigurations returns the configurations for every server that were
+// last returned by the Panel.
+func readCachedConfigurations() ([]remote.RawServerData, error) {
+	dir := config.Get().System.GetServerConfigCachePath()
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "server: could not read configuration cache directory")
+	}
+	var servers []remote.RawServerData
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var data remote.RawServerData
+		if err := json.Unmarshal(b, &data); err != nil {
+			log.WithFields(log.Fields{"file": f.Name(), "error": err}).Warn("failed to parse cached server configuration, skipping...")
+			continue
+		}
+		servers = append(servers, data)
+	}
+	return servers, nil
+}
+
+// parseServerData parses the raw configuration for a server returned by the
+// Panel into the structure used when creating and syncing servers.
+func parseServerData(data remote.RawServerData) (remote.ServerConfigurationResponse, error) {
+	d := remote.ServerConfigurationResponse{
+		Settings:                data.Settings,
+		RawProcessConfiguration: data.ProcessConfiguration,
+	}
+	if err := json.Unmarshal(data.ProcessConfiguration, &d.ProcessConfiguration); err != nil {
+		return d, errors.Wrap(err, "server: could not parse process configuration")
+	}
+	return d, nil
+}
+
+// loadCachedConfigurations returns the cached server configurations if the error
+// returned when fetching them from the Panel was because it could not be reached,
+// and starts syncing the servers with the Panel once it is back. The original
+// error is returned if the cache cannot be used.
+func (m *Manager) loadCachedConfigurations(ctx context.Context, err error) ([]remote.RawServerData, error) {
+	if !config.Get().RemoteQuery.CacheConfigurations || !remote.IsPanelUnavailable(err) || ctx.Err() != nil {
+		return nil, err
+	}
+	servers, cerr := readCachedConfigurations()
+	if cerr != nil {
+		log.WithField("error", cerr).Error("failed to read cached server configurations")
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"error": err, "total_configs": len(servers)}).Warn("panel is unreachable, booting servers from cached configurations")
+	go m.reconcileWithPanel(ctx)
+	return servers, nil
+}
+
+// reconcileWithPanel periodically attempts to fetch the servers from the Panel
+// after Wings was booted from the cached configurations. Once the Panel can be
+// reached every server is synced with the configuration it returns.
+func (m *Manager) reconcileWithPanel(ctx context.Context) {
+	t := time.NewTicker(time.Second * 30)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			servers, err := m.client.GetServers(ctx, config.Get().RemoteQuery.BootServersPerPage)
+			if err != nil {
+				log.WithField("error", err).Debug("panel is still unreachable, servers are running from cached configurations")
+				continue
+			}
+			m.reconcile(servers)
+			return
+		}
+	}
+}
+
+// reconcile syncs the servers that were booted from cached configurations with
+// the servers returned by the Panel. Servers that were created while the Panel
+// was unreachable are loaded, and servers that no longer exist are left alone
+// since they will not be loaded the next time Wings boots anyway.
+func (m *Manager) reconcile(servers []remote.RawServerData) {
+	log.WithField("total_configs", len(servers)).Info("panel is reachable again, syncing servers booted from cached configurations")
+	cacheConfigurations(servers)
+
+	found := make(map[string]bool, len(servers))
+	for _, data := range servers {
+		found[data.Uuid] = true
+		d, err := parseServerData(data)
+		if err != nil {
+			log.WithFields(log.Fields{"server": data.Uuid, "error": err}).Error("failed to parse server configuration from API response, skipping...")
+			continue
+		}
+		if s, ok := m.Get(data.Uuid); ok {
+			if err := s.SyncWithConfiguration(d); err != nil {
+				s.Log().WithField("error", err).Error("failed to sync server with configuration from panel")
+				continue
+			}
+			s.SyncWithEnvironment()
+			continue
+		}
+		s, err := m.InitServer(d)
+		if err != nil {
+			log.WithFields(log.Fields{"server": data.Uuid, "error": err}).Error("failed to load server, skipping...")
+			continue
+		}
+		if err := s.EnsureDataDirectoryExists(); err != nil {
+			s.Log().WithField("error", err).Warn("failed to create root data directory for server")
+		}
+		m.Add(s)
+	}
+	for _, s := range m.All() {
+		if !found[s.ID()] {
+			s.Log().Warn("server no longer exists on the panel and will not be loaded the next time wings boots")
+		}
+	}
+}
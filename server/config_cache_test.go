@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/remote"
+)
+
+// setCacheConfig enables configuration caching with all of the Wings directories
+// in a temporary directory.
+func setCacheConfig(t *testing.T, enabled bool) string {
+	dir := t.TempDir()
+	orig := config.Get()
+	t.Cleanup(func() {
+		config.Set(orig)
+	})
+	config.Update(func(c *config.Configuration) {
+		c.System.RootDirectory = dir
+		c.System.Data = filepath.Join(dir, "volumes")
+		c.System.LogDirectory = filepath.Join(dir, "logs")
+		c.RemoteQuery.CacheConfigurations = enabled
+	})
+	return config.Get().System.GetServerConfigCachePath()
+}
+
+func rawServerData(uuid string) remote.RawServerData {
+	return remote.RawServerData{
+		Uuid:                 uuid,
+		Settings:             json.RawMessage(`{"uuid":"` + uuid + `"}`),
+		ProcessConfiguration: json.RawMessage(`{"startup":{"done":["Done"]}}`),
+	}
+}
+
+func TestCacheConfigurations(t *testing.T) {
+	dir := setCacheConfig(t, true)
+
+	cacheConfigurations([]remote.RawServerData{rawServerData("a"), rawServerData("b")})
+	servers, err := readCachedConfigurations()
+	require.NoError(t, err)
+	require.Len(t, servers, 2)
+	assert.Equal(t, "a", servers[0].Uuid)
+	assert.JSONEq(t, `{"uuid":"a"}`, string(servers[0].Settings))
+
+	// Servers that were not returned by the Panel are removed from the cache, and
+	// servers without a process configuration are never cached.
+	cacheConfigurations([]remote.RawServerData{rawServerData("b"), {Uuid: "c"}})
+	servers, err = readCachedConfigurations()
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, "b", servers[0].Uuid)
+
+	// Files that cannot be parsed are skipped.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600))
+	servers, err = readCachedConfigurations()
+	require.NoError(t, err)
+	assert.Len(t, servers, 1)
+}
+
+func TestCacheConfigurationsDisabled(t *testing.T) {
+	dir := setCacheConfig(t, false)
+
+	cacheConfigurations([]remote.RawServerData{rawServerData("a")})
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	servers, err := readCachedConfigurations()
+	require.NoError(t, err)
+	assert.Empty(t, servers)
+}
+
+func TestManagerLoadCachedConfigurations(t *testing.T) {
+	setCacheConfig(t, true)
+	m := NewEmptyManager(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	unavailable := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	// Without any cached configurations the original error is returned.
+	_, err := m.loadCachedConfigurations(ctx, unavailable)
+	assert.Equal(t, unavailable, err)
+
+	cacheConfigurations([]remote.RawServerData{rawServerData("a")})
+	servers, err := m.loadCachedConfigurations(ctx, unavailable)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, "a", servers[0].Uuid)
+
+	// The cache is only used when the Panel could not be reached.
+	rejected := errors.New("invalid response")
+	_, err = m.loadCachedConfigurations(ctx, rejected)
+	assert.Equal(t, rejected, err)
+
+	config.Update(func(c *config.Configuration) {
+		c.RemoteQuery.CacheConfigurations = false
+	})
+	_, err = m.loadCachedConfigurations(ctx, unavailable)
+	assert.Equal(t, unavailable, err)
+}
+
+// syncEnvironment is an environment that accepts the settings synced to it from
+// the server.
+type syncEnvironment struct {
+	stateEnvironment
+	cfg     *environment.Configuration
+	updates int
+}
+
+func (e *syncEnvironment) Config() *environment.Configuration {
+	return e.cfg
+}
+
+func (e *syncEnvironment) InSituUpdate() error {
+	e.updates++
+	return nil
+}
+
+func TestManagerReconcile(t *testing.T) {
+	dir := setCacheConfig(t, true)
+	m := NewEmptyManager(nil)
+
+	existing := newTestServer(t)
+	existing.cfg.Uuid = "a"
+	env := &syncEnvironment{
+		stateEnvironment: stateEnvironment{state: environment.ProcessRunningState},
+		cfg:              environment.NewConfiguration(environment.Settings{}, nil),
+	}
+	existing.Environment = env
+	m.Add(existing)
+
+	removed := newTestServer(t)
+	removed.cfg.Uuid = "removed"
+	m.Add(removed)
+
+	a := rawServerData("a")
+	a.Settings = json.RawMessage(`{"uuid":"a","invocation":"./start.sh"}`)
+	m.reconcile([]remote.RawServerData{a, rawServerData("b")})
+	defer func() {
+		for _, s := range m.All() {
+			s.CtxCancel()
+		}
+	}()
+
+	// Existing servers are synced with the configuration returned by the Panel.
+	assert.Equal(t, "./start.sh", existing.Config().Invocation)
+	require.Len(t, existing.ProcessConfiguration().Startup.Done, 1)
+	assert.Equal(t, "Done", existing.ProcessConfiguration().Startup.Done[0].String())
+	assert.Equal(t, 1, env.updates)
+
+	// Servers created while the Panel was down are loaded, and servers that no
+	// longer exist are left alone.
+	_, ok := m.Get("b")
+	assert.True(t, ok)
+	_, ok = m.Get("removed")
+	assert.True(t, ok)
+	assert.Len(t, m.All(), 3)
+
+	// The cache now matches the servers returned by the Panel.
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}, files)
+}
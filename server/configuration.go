@@ -14,6 +14,13 @@ type EggConfiguration struct {
 	// or basically any type of access on the server by any user. This is NOT the same
 	// as a per-user denylist, this is defined at the Egg level.
 	FileDenylist []string `json:"file_denylist"`
+
+	// Console triggers that are defined by the egg and apply to every server that
+	// uses it.
+	Triggers []Trigger `json:"triggers"`
+
+	// Additional container options required by the egg, such as device access.
+	ContainerOptions environment.ContainerOptions `json:"container_options"`
 }
 
 type Configuration struct {
@@ -42,11 +49,28 @@ type Configuration struct {
 	EnvVars environment.Variables `json:"environment"`
 
 	Allocations           environment.Allocations `json:"allocations"`
+	Network               environment.Network     `json:"network"`
 	Build                 environment.Limits      `json:"build"`
 	CrashDetectionEnabled bool                    `json:"crash_detection_enabled"`
 	Mounts                []Mount                 `json:"mounts"`
 	Egg                   EggConfiguration        `json:"egg,omitempty"`
 
+	// Schedules that are executed locally by Wings for this server.
+	Schedules []Schedule `json:"schedules"`
+
+	// Console triggers defined for this specific server.
+	Triggers []Trigger `json:"triggers"`
+
+	// Additional container options for this server, merged with those of the egg.
+	ContainerOptions environment.ContainerOptions `json:"container_options"`
+
+	// The protocol used to query the server for the number of connected players.
+	Query QueryConfiguration `json:"query"`
+
+	// Defines if the server should be stopped while it is empty and started again
+	// when a client connects.
+	Hibernation HibernationConfiguration `json:"hibernation"`
+
 	Container struct {
 		// Defines the Docker image that will be used for this server
 		Image string `json:"image,omitempty"`
@@ -66,6 +90,14 @@ func (s *Server) DiskSpace() int64 {
 	return s.cfg.Build.DiskSpace * 1024.0 * 1024.0
 }
 
+// ContainerOptions returns the additional container options for the server, with
+// the options set for the server itself taking precedence over those of the egg.
+func (s *Server) ContainerOptions() environment.ContainerOptions {
+	s.cfg.mu.RLock()
+	defer s.cfg.mu.RUnlock()
+	return s.cfg.Egg.ContainerOptions.Merge(s.cfg.ContainerOptions)
+}
+
 func (s *Server) MemoryLimit() int64 {
 	s.cfg.mu.RLock()
 	defer s.cfg.mu.RUnlock()
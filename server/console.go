@@ -35,8 +35,30 @@ type ConsoleThrottler struct {
 	// determine what to do if it is.
 	isThrottled *system.AtomicBool
 
-	// The total number of lines processed so far during the given time period.
-	timerCancel *context.CancelFunc
+	// The context the timers were started with, and the function used to cancel
+	// them so that they can be restarted if the intervals are changed.
+	timerCtx    context.Context
+	timerCancel context.CancelFunc
+}
+
+// throttles returns the current throttle configuration.
+func (ct *ConsoleThrottler) throttles() config.ConsoleThrottles {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.ConsoleThrottles
+}
+
+// SetConfiguration replaces the throttle configuration for the server, restarting
+// the timers if their intervals were changed.
+func (ct *ConsoleThrottler) SetConfiguration(c config.ConsoleThrottles) {
+	ct.mu.Lock()
+	restart := ct.timerCtx != nil && (c.LineResetInterval != ct.LineResetInterval || c.DecayInterval != ct.DecayInterval)
+	ct.ConsoleThrottles = c
+	ctx := ct.timerCtx
+	ct.mu.Unlock()
+	if restart {
+		ct.StartTimer(ctx)
+	}
 }
 
 // Resets the state of the throttler.
@@ -71,12 +93,22 @@ func (ct *ConsoleThrottler) Throttled() bool {
 // and number of activations, regardless of the current console message volume. All of the timers
 // are canceled if the context passed through is canceled.
 func (ct *ConsoleThrottler) StartTimer(ctx context.Context) {
-	system.Every(ctx, time.Duration(int64(ct.LineResetInterval))*time.Millisecond, func(_ time.Time) {
+	ct.mu.Lock()
+	if ct.timerCancel != nil {
+		ct.timerCancel()
+	}
+	ct.timerCtx = ctx
+	tctx, cancel := context.WithCancel(ctx)
+	ct.timerCancel = cancel
+	c := ct.ConsoleThrottles
+	ct.mu.Unlock()
+
+	system.Every(tctx, time.Duration(int64(c.LineResetInterval))*time.Millisecond, func(_ time.Time) {
 		ct.isThrottled.Store(false)
 		atomic.StoreUint64(&ct.count, 0)
 	})
 
-	system.Every(ctx, time.Duration(int64(ct.DecayInterval))*time.Millisecond, func(_ time.Time) {
+	system.Every(tctx, time.Duration(int64(c.DecayInterval))*time.Millisecond, func(_ time.Time) {
 		ct.markActivation(false)
 	})
 }
@@ -98,16 +130,17 @@ func (ct *ConsoleThrottler) StartTimer(ctx context.Context) {
 // This function returns an error if the server should be stopped due to violating throttle constraints
 // and a boolean value indicating if a throttle is being violated when it is checked.
 func (ct *ConsoleThrottler) Increment(onTrigger func()) error {
-	if !ct.Enabled {
+	c := ct.throttles()
+	if !c.Enabled {
 		return nil
 	}
 
 	// Increment the line count and if we have now output more lines than are allowed, trigger a throttle
 	// activation. Once the throttle is triggered and has passed the kill at value we will trigger a server
 	// stop automatically.
-	if atomic.AddUint64(&ct.count, 1) >= ct.Lines && !ct.Throttled() {
+	if atomic.AddUint64(&ct.count, 1) >= c.Lines && !ct.Throttled() {
 		ct.isThrottled.Store(true)
-		if ct.markActivation(true) >= ct.MaximumTriggerCount {
+		if ct.markActivation(true) >= c.MaximumTriggerCount {
 			return ErrTooMuchConsoleData
 		}
 
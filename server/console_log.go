@@ -0,0 +1,77 @@
+package server
+
+import (
+	"time"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/events"
+	"github.com/pterodactyl/wings/server/consolelog"
+)
+
+// The number of lines of console output that can be waiting to be written to
+// the persistent log before further lines are dropped.
+const consoleLogQueueSize = 1024
+
+// ConsoleLog returns the persistent console log for the server. If console
+// logging is disabled in the configuration nil is returned.
+func (s *Server) ConsoleLog() *consolelog.Log {
+	s.RLock()
+	defer s.RUnlock()
+	return s.consoleLog
+}
+
+// startConsoleLog opens the persistent console log for the server and begins
+// writing all console output into it. The log is flushed to the disk every
+// second and closed once the server context is canceled.
+func (s *Server) startConsoleLog() error {
+	cfg := config.Get().System
+	if !cfg.ConsoleLogs.Enabled {
+		return nil
+	}
+
+	l, err := consolelog.New(cfg.GetConsoleLogPath(s.ID()), cfg.ConsoleLogs.MaxSize*1024*1024, cfg.ConsoleLogs.MaxFiles)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	s.consoleLog = l
+	s.Unlock()
+
+	// Console output is handed off to a separate goroutine to be written so that
+	// disk IO never holds up the event worker shared by every listener. If that
+	// goroutine falls behind the output is dropped rather than blocking.
+	entries := make(chan consolelog.Entry, consoleLogQueueSize)
+	fn := func(e events.Event) {
+		select {
+		case entries <- consolelog.Entry{Time: time.Now(), Line: e.Data}:
+		default:
+			s.Log().Debug("persistent console log queue is full, dropping line of output")
+		}
+	}
+	s.Events().On(ConsoleOutputEvent, &fn)
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case e := <-entries:
+				if err := l.Write(e.Time, e.Line); err != nil {
+					s.Log().WithField("error", err).Warn("failed to write console output to persistent log")
+				}
+			case <-ticker.C:
+				if err := l.Flush(); err != nil {
+					s.Log().WithField("error", err).Warn("failed to flush persistent console log to disk")
+				}
+			case <-s.Context().Done():
+				s.Events().Off(ConsoleOutputEvent, &fn)
+				if err := l.Close(); err != nil {
+					s.Log().WithField("error", err).Warn("failed to close persistent console log")
+				}
+				return
+			}
+		}
+	}()
+
+	return nil
+}
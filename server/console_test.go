@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/system"
+)
+
+func TestConsoleThrottlerSetConfiguration(t *testing.T) {
+	c := config.ConsoleThrottles{
+		Enabled:             true,
+		Lines:               2,
+		MaximumTriggerCount: 5,
+		LineResetInterval:   uint64(time.Hour.Milliseconds()),
+		DecayInterval:       uint64(time.Hour.Milliseconds()),
+	}
+	ct := &ConsoleThrottler{isThrottled: system.NewAtomicBool(false), ConsoleThrottles: c}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ct.StartTimer(ctx)
+
+	assert.NoError(t, ct.Increment(func() {}))
+	assert.NoError(t, ct.Increment(func() {}))
+	assert.True(t, ct.Throttled())
+
+	// Changing the interval restarts the timers with the new value, so the
+	// throttle is reset without waiting on the old hour long interval.
+	c.LineResetInterval = 10
+	ct.SetConfiguration(c)
+	assert.Eventually(t, func() bool { return !ct.Throttled() }, time.Second, time.Millisecond*5)
+
+	// The new line limit is used straight away.
+	c.Enabled = true
+	c.Lines = 1000
+	c.LineResetInterval = uint64(time.Hour.Milliseconds())
+	ct.SetConfiguration(c)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, ct.Increment(func() {}))
+	}
+	assert.False(t, ct.Throttled())
+
+	c.Enabled = false
+	c.Lines = 1
+	ct.SetConfiguration(c)
+	assert.NoError(t, ct.Increment(func() {}))
+	assert.False(t, ct.Throttled())
+}
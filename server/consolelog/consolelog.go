@@ -0,0 +1,426 @@
+package consolelog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/system"
+)
+
+const currentFile = "console.log"
+
+// Entry is a single line of console output along with the time at which it was
+// written to the log. Every entry has a unique sequence number that increases
+// with each line written, which is used to page through the history.
+type Entry struct {
+	Sequence uint64    `json:"sequence"`
+	Time     time.Time `json:"time"`
+	Line     string    `json:"line"`
+}
+
+// Query defines the filters to apply when searching through the console history
+// for a server. Results are always returned newest first, paging backwards by
+// passing the sequence of the oldest entry returned as the next Before value.
+type Query struct {
+	// Only entries with a sequence lower than this are returned. A zero value means
+	// there is no upper bound.
+	Before uint64
+
+	// Only entries written at or after this time are returned. A zero value means
+	// there is no lower bound.
+	Since time.Time
+
+	// Only entries written strictly before this time are returned. A zero value
+	// means there is no upper bound.
+	Until time.Time
+
+	// An optional expression to match lines against. ANSI escape codes are removed
+	// from the line before it is checked.
+	Search *regexp.Regexp
+
+	// The maximum number of entries to return.
+	Limit int
+}
+
+// Log is a rotating, compressed console log for a single server. The active file
+// is written as plain text, once it reaches the maximum size it is compressed and
+// a new file is started. Only the configured number of compressed files are kept.
+type Log struct {
+	mu       sync.Mutex
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	f    *os.File
+	w    *bufio.Writer
+	size int64
+	seq  uint64
+}
+
+// New returns a console log writing into the given directory, creating it if it
+// does not already exist.
+func New(dir string, maxSize int64, maxFiles int) (*Log, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "consolelog: failed to create log directory")
+	}
+	l := &Log{dir: dir, maxSize: maxSize, maxFiles: maxFiles}
+	seq, err := l.lastSequence()
+	if err != nil {
+		return nil, err
+	}
+	l.seq = seq
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// lastSequence returns the sequence of the newest entry in the log so that new
+// entries continue on from it, or zero if the log is empty.
+func (l *Log) lastSequence() (uint64, error) {
+	archives, err := l.archives()
+	if err != nil {
+		return 0, err
+	}
+	files := append([]archive{{path: filepath.Join(l.dir, currentFile)}}, archives...)
+	for _, a := range files {
+		var seq uint64
+		err := scanFile(a.path, !a.rotated.IsZero(), func(e Entry) {
+			seq = e.Sequence
+		})
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return 0, err
+		}
+		if seq > 0 {
+			return seq, nil
+		}
+	}
+	return 0, nil
+}
+
+// Path returns the directory that this log is written to.
+func (l *Log) Path() string {
+	return l.dir
+}
+
+func (l *Log) open() error {
+	f, err := os.OpenFile(filepath.Join(l.dir, currentFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "consolelog: failed to open log file")
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.WithStack(err)
+	}
+	l.f = f
+	l.w = bufio.NewWriter(f)
+	l.size = st.Size()
+	return nil
+}
+
+// Write appends a line of console output to the log, rotating the file first if
+// it has grown beyond the maximum size.
+func (l *Log) Write(t time.Time, line string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return errors.New("consolelog: log is closed")
+	}
+	if l.maxSize > 0 && l.size >= l.maxSize {
+		if err := l.rotate(t); err != nil {
+			return err
+		}
+	}
+	l.seq++
+	n, err := fmt.Fprintf(l.w, "%d\t%d\t%s\n", l.seq, t.UnixNano(), strings.ReplaceAll(line, "\n", " "))
+	l.size += int64(n)
+	return errors.WithStack(err)
+}
+
+// Flush writes any buffered output to the disk.
+func (l *Log) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w == nil {
+		return nil
+	}
+	return errors.WithStack(l.w.Flush())
+}
+
+// Close flushes the log and closes the underlying file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	_ = l.w.Flush()
+	err := l.f.Close()
+	l.f, l.w = nil, nil
+	return errors.WithStack(err)
+}
+
+// rotate compresses the current log file into an archive named after the time
+// of the rotation and then prunes the oldest archives. This must be called while
+// holding the lock.
+func (l *Log) rotate(t time.Time) error {
+	if err := l.w.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := l.f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	l.f, l.w = nil, nil
+
+	src := filepath.Join(l.dir, currentFile)
+	// Never overwrite an existing archive if two rotations happen at the same time.
+	dst := filepath.Join(l.dir, fmt.Sprintf("console-%d.log.gz", t.UnixNano()))
+	for {
+		if _, err := os.Stat(dst); os.IsNotExist(err) {
+			break
+		}
+		t = t.Add(time.Nanosecond)
+		dst = filepath.Join(l.dir, fmt.Sprintf("console-%d.log.gz", t.UnixNano()))
+	}
+	if err := compress(src, dst); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return errors.WithStack(err)
+	}
+
+	archives, err := l.archives()
+	if err != nil {
+		return err
+	}
+	if l.maxFiles >= 0 && len(archives) > l.maxFiles {
+		for _, a := range archives[l.maxFiles:] {
+			_ = os.Remove(a.path)
+		}
+	}
+	return l.open()
+}
+
+func compress(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer out.Close()
+	gw := gzip.NewWriter(out)
+	if _, err := io.Copy(gw, in); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(gw.Close())
+}
+
+type archive struct {
+	path string
+	// The time the archive was rotated, which is the upper bound on any entry
+	// contained within it.
+	rotated time.Time
+}
+
+// archives returns all of the compressed log files for the server ordered from
+// the newest to the oldest.
+func (l *Log) archives() ([]archive, error) {
+	matches, err := filepath.Glob(filepath.Join(l.dir, "console-*.log.gz"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var out []archive
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), "console-"), ".log.gz")
+		n, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, archive{path: m, rotated: time.Unix(0, n)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].rotated.After(out[j].rotated)
+	})
+	return out, nil
+}
+
+// segment is a log file opened for a search along with the size it had when it
+// was opened.
+type segment struct {
+	archive
+	f    *os.File
+	size int64
+}
+
+// snapshot flushes the log and opens the active file and every archive while
+// holding the lock, so a rotation during a search can neither move lines into a
+// file that was not listed nor remove a file before it is read. Open files stay
+// readable once removed, and each is only read up to its size at this point.
+func (l *Log) snapshot() ([]segment, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w != nil {
+		if err := l.w.Flush(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	archives, err := l.archives()
+	if err != nil {
+		return nil, err
+	}
+	var out []segment
+	for _, a := range append([]archive{{path: filepath.Join(l.dir, currentFile)}}, archives...) {
+		f, err := os.Open(a.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			closeSegments(out)
+			return nil, errors.WithStack(err)
+		}
+		st, err := f.Stat()
+		if err != nil {
+			f.Close()
+			closeSegments(out)
+			return nil, errors.WithStack(err)
+		}
+		out = append(out, segment{archive: a, f: f, size: st.Size()})
+	}
+	return out, nil
+}
+
+func closeSegments(segments []segment) {
+	for _, sg := range segments {
+		_ = sg.f.Close()
+	}
+}
+
+// Search returns the entries matching the query, newest first. Files are read
+// as a stream and only the newest matching entries of each file are kept, so
+// the memory used is bounded by the limit of the query rather than by the size
+// of the files.
+func (l *Log) Search(q Query) ([]Entry, error) {
+	segments, err := l.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer closeSegments(segments)
+	return search(segments, q)
+}
+
+// search returns the entries matching the query from the segments, which are
+// ordered from the newest to the oldest.
+func search(segments []segment, q Query) ([]Entry, error) {
+	out := make([]Entry, 0, q.Limit)
+	for _, sg := range segments {
+		// Archives are named by the time they were rotated, so if that is before the
+		// lower bound nothing in this file, or any older file, can match.
+		if !sg.rotated.IsZero() && !q.Since.IsZero() && sg.rotated.Before(q.Since) {
+			break
+		}
+		remaining := 0
+		if q.Limit > 0 {
+			remaining = q.Limit - len(out)
+		}
+		// The newest matching entries of the file, kept as a ring buffer once there
+		// are as many as needed so that older entries are overwritten.
+		var older bool
+		var matches []Entry
+		var next int
+		err := scan(io.LimitReader(sg.f, sg.size), !sg.rotated.IsZero(), func(e Entry) {
+			if q.Before > 0 && e.Sequence >= q.Before {
+				return
+			}
+			if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+				return
+			}
+			if !q.Since.IsZero() && e.Time.Before(q.Since) {
+				older = true
+				return
+			}
+			if q.Search != nil && !q.Search.MatchString(system.StripAnsiRegex.ReplaceAllString(e.Line, "")) {
+				return
+			}
+			if remaining > 0 && len(matches) == remaining {
+				matches[next] = e
+				next = (next + 1) % remaining
+				return
+			}
+			matches = append(matches, e)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := len(matches) - 1; i >= 0; i-- {
+			out = append(out, matches[(next+i)%len(matches)])
+		}
+		if (q.Limit > 0 && len(out) >= q.Limit) || older {
+			break
+		}
+	}
+	return out, nil
+}
+
+// scanFile reads the entries from a single log file in the order they were
+// written, calling fn for each of them.
+func scanFile(p string, compressed bool, fn func(e Entry)) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return scan(f, compressed, fn)
+}
+
+// scan reads the entries from a log file in the order they were written, calling
+// fn for each of them.
+func scan(r io.Reader, compressed bool, fn func(e Entry)) error {
+	if compressed {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		seq, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		fn(Entry{Sequence: seq, Time: time.Unix(0, n), Line: parts[2]})
+	}
+	return errors.WithStack(scanner.Err())
+}
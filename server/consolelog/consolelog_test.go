@@ -0,0 +1,144 @@
+package consolelog
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSearch(t *testing.T) {
+	l, err := New(t.TempDir(), 0, 5)
+	require.NoError(t, err)
+	defer l.Close()
+
+	base := time.Unix(1600000000, 0)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, l.Write(base.Add(time.Duration(i)*time.Second), fmt.Sprintf("\x1b[33mline %d", i)))
+	}
+
+	out, err := l.Search(Query{Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, out, 3)
+	assert.Equal(t, "\x1b[33mline 9", out[0].Line)
+	assert.Equal(t, "\x1b[33mline 7", out[2].Line)
+
+	// Page backwards from the oldest entry returned.
+	out, err = l.Search(Query{Before: out[2].Sequence, Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, out, 3)
+	assert.Equal(t, "\x1b[33mline 6", out[0].Line)
+
+	out, err = l.Search(Query{Until: base.Add(2 * time.Second)})
+	require.NoError(t, err)
+	assert.Len(t, out, 2)
+
+	out, err = l.Search(Query{Since: base.Add(8 * time.Second)})
+	require.NoError(t, err)
+	assert.Len(t, out, 2)
+
+	out, err = l.Search(Query{Search: regexp.MustCompile(`^line [13]$`)})
+	require.NoError(t, err)
+	require.Len(t, out, 2)
+	assert.Equal(t, "\x1b[33mline 3", out[0].Line)
+}
+
+func TestLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(dir, 64, 2)
+	require.NoError(t, err)
+	defer l.Close()
+
+	base := time.Unix(1600000000, 0)
+	for i := 0; i < 50; i++ {
+		require.NoError(t, l.Write(base.Add(time.Duration(i)*time.Second), fmt.Sprintf("line %d", i)))
+	}
+
+	archives, err := filepath.Glob(filepath.Join(dir, "console-*.log.gz"))
+	require.NoError(t, err)
+	assert.Len(t, archives, 2)
+
+	// Entries spanning the active file and the remaining archives are all returned
+	// in order, newest first.
+	out, err := l.Search(Query{})
+	require.NoError(t, err)
+	require.NotEmpty(t, out)
+	assert.Equal(t, "line 49", out[0].Line)
+	for i := 1; i < len(out); i++ {
+		assert.True(t, out[i].Time.Before(out[i-1].Time))
+	}
+}
+
+func TestLogSearchPagesEntriesWithTheSameTime(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(dir, 64, 10)
+	require.NoError(t, err)
+
+	// Every entry is written at the same time and spread across several files, none
+	// may be skipped or repeated when paging through them.
+	now := time.Unix(1600000000, 0)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, l.Write(now, fmt.Sprintf("line %d", i)))
+	}
+
+	var lines []string
+	var before uint64
+	for {
+		out, err := l.Search(Query{Before: before, Limit: 3})
+		require.NoError(t, err)
+		for _, e := range out {
+			lines = append(lines, e.Line)
+		}
+		if len(out) < 3 {
+			break
+		}
+		before = out[len(out)-1].Sequence
+	}
+	require.Len(t, lines, 20)
+	for i, line := range lines {
+		assert.Equal(t, fmt.Sprintf("line %d", 19-i), line)
+	}
+
+	// Sequences continue on from the existing entries when the log is opened again.
+	require.NoError(t, l.Close())
+	l, err = New(dir, 64, 10)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Write(now, "line 20"))
+	out, err := l.Search(Query{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	assert.Equal(t, uint64(21), out[0].Sequence)
+}
+
+func TestLogSearchDuringRotation(t *testing.T) {
+	l, err := New(t.TempDir(), 64, 2)
+	require.NoError(t, err)
+	defer l.Close()
+
+	base := time.Unix(1600000000, 0)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.Write(base.Add(time.Duration(i)*time.Second), fmt.Sprintf("line %d", i)))
+	}
+	segments, err := l.snapshot()
+	require.NoError(t, err)
+	defer closeSegments(segments)
+
+	// The next line is written to the active file in the snapshot, then the log is
+	// rotated enough times that every file in it has been removed before it is read.
+	for i := 5; i < 30; i++ {
+		require.NoError(t, l.Write(base.Add(time.Duration(i)*time.Second), fmt.Sprintf("line %d", i)))
+	}
+
+	out, err := search(segments, Query{})
+	require.NoError(t, err)
+	require.Len(t, out, 5)
+	for i, e := range out {
+		assert.Equal(t, uint64(5-i), e.Sequence)
+		assert.Equal(t, fmt.Sprintf("line %d", 4-i), e.Line)
+	}
+}
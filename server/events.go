@@ -11,6 +11,7 @@ const (
 	InstallOutputEvent          = "install output"
 	InstallStartedEvent         = "install started"
 	InstallCompletedEvent       = "install completed"
+	InstallProgressEvent        = "install progress"
 	ConsoleOutputEvent          = "console output"
 	StatusEvent                 = "status"
 	StatsEvent                  = "stats"
@@ -18,6 +19,7 @@ const (
 	BackupCompletedEvent        = "backup completed"
 	TransferLogsEvent           = "transfer logs"
 	TransferStatusEvent         = "transfer status"
+	TriggerEvent                = "console trigger"
 )
 
 // Returns the server's emitter instance.
@@ -38,6 +38,12 @@ type Filesystem struct {
 	// The root data directory path for this Filesystem instance.
 	root string
 
+	// The user and group that files are owned by. If zero the Pterodactyl user
+	// for the node is used.
+	ownerMu sync.RWMutex
+	uid     int
+	gid     int
+
 	isTest bool
 }
 
@@ -53,6 +59,23 @@ func New(root string, size int64, denylist []string) *Filesystem {
 	}
 }
 
+// SetOwner sets the user and group that the files for the server are owned by.
+func (fs *Filesystem) SetOwner(uid int, gid int) {
+	fs.ownerMu.Lock()
+	fs.uid, fs.gid = uid, gid
+	fs.ownerMu.Unlock()
+}
+
+// Owner returns the user and group that the files for the server are owned by.
+func (fs *Filesystem) Owner() (int, int) {
+	fs.ownerMu.RLock()
+	defer fs.ownerMu.RUnlock()
+	if fs.uid == 0 {
+		return config.Get().System.User.Uid, config.Get().System.User.Gid
+	}
+	return fs.uid, fs.gid
+}
+
 // Path returns the root path for the Filesystem instance.
 func (fs *Filesystem) Path() string {
 	return fs.root
@@ -233,8 +256,7 @@ func (fs *Filesystem) Chown(path string) error {
 		return nil
 	}
 
-	uid := config.Get().System.User.Uid
-	gid := config.Get().System.User.Gid
+	uid, gid := fs.Owner()
 
 	if runtime.GOOS == "windows" {
 		return nil
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/system"
+)
+
+// HibernationConfiguration defines if a server should be stopped automatically
+// once it has been empty for a period of time.
+type HibernationConfiguration struct {
+	Enabled bool `json:"enabled"`
+
+	// The number of minutes the server must have no players connected before it
+	// is stopped.
+	IdleMinutes int `json:"idle_minutes"`
+}
+
+// HibernatingState is persisted as the state of a server that is hibernating so
+// that Wings can listen for connections to it again after being restarted. It
+// is never used as the state of the environment itself.
+const HibernatingState = "hibernating"
+
+// Hibernator stops a server once it has been empty for the configured amount of
+// time and then listens on the default allocation for the server itself. When a
+// client connects the connection is dropped and the server is started again, so
+// the client only needs to retry once the server has booted.
+//
+// The number of players is determined by querying the server, so hibernation is
+// only possible for servers that have a query protocol configured.
+type Hibernator struct {
+	mu          sync.Mutex
+	server      *Server
+	emptySince  time.Time
+	hibernating bool
+	listeners   []io.Closer
+
+	// Runs a power action for the server, waiting on the power lock if needed.
+	power func(action PowerAction) error
+}
+
+// Hibernator returns the hibernator instance for the server or creates a new one.
+func (s *Server) Hibernator() *Hibernator {
+	s.hibernatorOnce.Do(func() {
+		s.hibernator = &Hibernator{
+			server: s,
+			power: func(action PowerAction) error {
+				return s.HandlePowerAction(action, 30)
+			},
+		}
+	})
+	return s.hibernator
+}
+
+// hibernation returns the hibernation configuration for the server.
+func (s *Server) hibernation() HibernationConfiguration {
+	s.cfg.mu.RLock()
+	defer s.cfg.mu.RUnlock()
+	return s.cfg.Hibernation
+}
+
+// IsHibernating returns true if the server was stopped because it was idle and
+// Wings is currently waiting for a connection to start it again.
+func (h *Hibernator) IsHibernating() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hibernating
+}
+
+// StartTimer begins checking the number of players on the server once a minute
+// and hibernates it once it has been empty for long enough.
+func (h *Hibernator) StartTimer(ctx context.Context) {
+	system.Every(ctx, time.Minute, func(t time.Time) {
+		h.check(t)
+	})
+	go func() {
+		<-ctx.Done()
+		h.release()
+	}()
+}
+
+func (h *Hibernator) check(t time.Time) {
+	s := h.server
+	cfg := s.hibernation()
+	p := s.Players()
+
+	h.mu.Lock()
+	if !cfg.Enabled || cfg.IdleMinutes <= 0 || h.hibernating || s.Environment.State() != environment.ProcessRunningState || p == nil || p.Online > 0 {
+		h.emptySince = time.Time{}
+		h.mu.Unlock()
+		return
+	}
+	if h.emptySince.IsZero() {
+		h.emptySince = t
+	}
+	idle := t.Sub(h.emptySince) >= time.Duration(cfg.IdleMinutes)*time.Minute
+	h.mu.Unlock()
+
+	if idle {
+		if err := h.Hibernate(); err != nil {
+			s.Log().WithField("error", err).Error("failed to hibernate idle server")
+		}
+	}
+}
+
+// Hibernate stops the server and begins listening for connections on the default
+// allocation for it.
+func (h *Hibernator) Hibernate() error {
+	s := h.server
+	s.Log().Info("server has no players connected, hibernating server")
+	s.PublishConsoleOutputFromDaemon("Server has been empty for too long and is being hibernated, it will start again when a player connects.")
+	if err := h.power(PowerActionStop); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.emptySince = time.Time{}
+	if err := h.listen(); err != nil {
+		h.closeListeners()
+		return errors.WrapIf(err, "server/hibernate: failed to listen on allocation")
+	}
+	h.hibernating = true
+	return nil
+}
+
+// Resume begins listening for connections on the default allocation for a server
+// that was hibernating when Wings was last stopped. Nothing is done if the server
+// no longer has hibernation enabled.
+func (h *Hibernator) Resume() error {
+	if !h.server.hibernation().Enabled {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.hibernating {
+		return nil
+	}
+	if err := h.listen(); err != nil {
+		h.closeListeners()
+		return errors.WrapIf(err, "server/hibernate: failed to listen on allocation")
+	}
+	h.hibernating = true
+	return nil
+}
+
+// listen opens a TCP and UDP listener on the default allocation for the server.
+// This must be called while holding the lock.
+func (h *Hibernator) listen() error {
+	a := h.server.Config().Allocations.DefaultMapping
+	addr := net.JoinHostPort(a.Ip, strconv.Itoa(a.Port))
+
+	tl, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	h.listeners = append(h.listeners, tl)
+	go func() {
+		conn, err := tl.Accept()
+		if err != nil {
+			return
+		}
+		h.wake(conn.RemoteAddr())
+		_ = conn.Close()
+	}()
+
+	ul, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	h.listeners = append(h.listeners, ul)
+	go func() {
+		buf := make([]byte, 1)
+		_, from, err := ul.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		h.wake(from)
+	}()
+	return nil
+}
+
+// wake starts the server once a client has attempted to connect to it while it
+// was hibernating.
+func (h *Hibernator) wake(from net.Addr) {
+	h.mu.Lock()
+	if !h.hibernating {
+		h.mu.Unlock()
+		return
+	}
+	h.hibernating = false
+	h.closeListeners()
+	h.mu.Unlock()
+
+	s := h.server
+	s.Log().WithField("remote", from.String()).Info("received connection to hibernating server, starting server")
+	go func() {
+		if err := h.power(PowerActionStart); err != nil {
+			s.Log().WithField("error", err).Error("failed to start server after waking from hibernation")
+		}
+	}()
+}
+
+// release stops listening on the allocation for the server if it is currently
+// hibernating. This is called before the server is started so that the port is
+// available for the server process to bind to.
+func (h *Hibernator) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hibernating = false
+	h.closeListeners()
+}
+
+func (h *Hibernator) closeListeners() {
+	for _, l := range h.listeners {
+		_ = l.Close()
+	}
+	h.listeners = nil
+}
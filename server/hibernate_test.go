@@ -0,0 +1,156 @@
+package server
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/server/query"
+)
+
+// stateEnvironment is an environment that only reports a state. Calling any of
+// the other methods panics.
+type stateEnvironment struct {
+	environment.ProcessEnvironment
+	state string
+}
+
+func (e *stateEnvironment) State() string {
+	return e.state
+}
+
+// newHibernatingServer returns a running test server with hibernation enabled
+// on a free local port, along with the power actions run by the hibernator.
+func newHibernatingServer(t *testing.T) (*Server, func() []PowerAction) {
+	s := newTestServer(t)
+	s.Environment = &stateEnvironment{state: environment.ProcessRunningState}
+	s.cfg.Hibernation = HibernationConfiguration{Enabled: true, IdleMinutes: 5}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.cfg.Allocations.DefaultMapping.Ip = "127.0.0.1"
+	s.cfg.Allocations.DefaultMapping.Port = l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	var mu sync.Mutex
+	var actions []PowerAction
+	h := s.Hibernator()
+	h.power = func(a PowerAction) error {
+		mu.Lock()
+		actions = append(actions, a)
+		mu.Unlock()
+		return nil
+	}
+	t.Cleanup(h.release)
+	return s, func() []PowerAction {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]PowerAction{}, actions...)
+	}
+}
+
+func TestHibernatorCheck(t *testing.T) {
+	s, actions := newHibernatingServer(t)
+	h := s.Hibernator()
+	now := time.Now()
+
+	// Nothing happens until the number of players is known.
+	h.check(now)
+	h.check(now.Add(time.Hour))
+	assert.Empty(t, actions())
+
+	s.resources.Players = &query.Players{Online: 1}
+	h.check(now)
+	s.resources.Players = &query.Players{}
+	h.check(now.Add(time.Minute))
+	h.check(now.Add(time.Minute * 5))
+	assert.Empty(t, actions())
+
+	// A player connecting resets the idle time.
+	s.resources.Players = &query.Players{Online: 1}
+	h.check(now.Add(time.Minute * 6))
+	s.resources.Players = &query.Players{}
+	h.check(now.Add(time.Minute * 7))
+	h.check(now.Add(time.Minute * 11))
+	assert.Empty(t, actions())
+
+	h.check(now.Add(time.Minute * 12))
+	assert.Equal(t, []PowerAction{PowerActionStop}, actions())
+	assert.True(t, h.IsHibernating())
+
+	// Servers that are not running are never hibernated.
+	h.release()
+	s.Environment.(*stateEnvironment).state = environment.ProcessOfflineState
+	h.check(now.Add(time.Minute * 20))
+	h.check(now.Add(time.Minute * 30))
+	assert.Len(t, actions(), 1)
+}
+
+func TestHibernatorWakesOnConnection(t *testing.T) {
+	for _, network := range []string{"tcp", "udp"} {
+		t.Run(network, func(t *testing.T) {
+			s, actions := newHibernatingServer(t)
+			h := s.Hibernator()
+			require.NoError(t, h.Hibernate())
+			assert.True(t, h.IsHibernating())
+
+			addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(s.cfg.Allocations.DefaultMapping.Port))
+			conn, err := net.Dial(network, addr)
+			require.NoError(t, err)
+			_, _ = conn.Write([]byte{1})
+			_ = conn.Close()
+
+			assert.Eventually(t, func() bool {
+				return len(actions()) == 2
+			}, time.Second, time.Millisecond*10)
+			assert.Equal(t, []PowerAction{PowerActionStop, PowerActionStart}, actions())
+			assert.False(t, h.IsHibernating())
+
+			// The listeners are closed so the server process can use the port.
+			l, err := net.Listen("tcp", addr)
+			require.NoError(t, err)
+			_ = l.Close()
+		})
+	}
+}
+
+func TestHibernatorResume(t *testing.T) {
+	s, actions := newHibernatingServer(t)
+	h := s.Hibernator()
+
+	require.NoError(t, h.Resume())
+	assert.True(t, h.IsHibernating())
+	assert.Empty(t, actions())
+	_, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(s.cfg.Allocations.DefaultMapping.Port)))
+	assert.Error(t, err)
+
+	h.release()
+	s.cfg.Hibernation.Enabled = false
+	require.NoError(t, h.Resume())
+	assert.False(t, h.IsHibernating())
+}
+
+func TestHibernatorDisabledWhileHibernating(t *testing.T) {
+	s, _ := newHibernatingServer(t)
+	h := s.Hibernator()
+	require.NoError(t, h.Hibernate())
+	require.True(t, h.IsHibernating())
+
+	// Updates that leave hibernation enabled do not wake the server.
+	require.NoError(t, s.UpdateDataStructure([]byte(`{"hibernation":{"enabled":true,"idle_minutes":10}}`)))
+	assert.True(t, h.IsHibernating())
+
+	require.NoError(t, s.UpdateDataStructure([]byte(`{"hibernation":{"enabled":false}}`)))
+	assert.False(t, h.IsHibernating())
+
+	// The allocation is no longer held open by the hibernator.
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(s.Config().Allocations.DefaultMapping.Port)))
+	require.NoError(t, err)
+	_ = l.Close()
+}
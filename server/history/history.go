@@ -0,0 +1,215 @@
+// Package history stores downsampled resource usage for a server so that past
+// usage can be queried after the live stats have been overwritten.
+package history
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// Point is the resource usage of a server over a single interval. CPU and memory
+// usage are averaged over the interval, all other values are the last value seen
+// during it.
+type Point struct {
+	Time        time.Time `json:"time"`
+	CpuAbsolute float64   `json:"cpu_absolute"`
+	Memory      uint64    `json:"memory_bytes"`
+	MemoryLimit uint64    `json:"memory_limit_bytes"`
+	RxBytes     uint64    `json:"rx_bytes"`
+	TxBytes     uint64    `json:"tx_bytes"`
+	Disk        int64     `json:"disk_bytes"`
+}
+
+// series is a fixed size ring of points at a single resolution. Incoming samples
+// are aggregated into the current interval and only pushed into the ring once a
+// sample for a later interval arrives.
+type series struct {
+	Resolution time.Duration
+	Points     []Point
+	Head       int
+	Full       bool
+
+	// The interval currently being aggregated.
+	Current Point
+	Samples int
+}
+
+func newSeries(resolution time.Duration, retention time.Duration) *series {
+	return &series{Resolution: resolution, Points: make([]Point, int(retention/resolution))}
+}
+
+func (s *series) add(t time.Time, p Point) {
+	bucket := t.Truncate(s.Resolution)
+	if s.Samples > 0 && !bucket.Equal(s.Current.Time) {
+		s.push(s.Current)
+		s.Samples = 0
+	}
+	if s.Samples == 0 {
+		s.Current = Point{Time: bucket}
+	}
+	// Keep a running average for the usage values and the latest value for
+	// everything else.
+	n := float64(s.Samples)
+	s.Current.CpuAbsolute = (s.Current.CpuAbsolute*n + p.CpuAbsolute) / (n + 1)
+	s.Current.Memory = uint64((float64(s.Current.Memory)*n + float64(p.Memory)) / (n + 1))
+	s.Current.MemoryLimit = p.MemoryLimit
+	s.Current.RxBytes = p.RxBytes
+	s.Current.TxBytes = p.TxBytes
+	s.Current.Disk = p.Disk
+	s.Samples++
+}
+
+func (s *series) push(p Point) {
+	s.Points[s.Head] = p
+	s.Head = (s.Head + 1) % len(s.Points)
+	if s.Head == 0 {
+		s.Full = true
+	}
+}
+
+// between returns the points in the series within the given range in order from
+// oldest to newest, including the interval that is still being aggregated.
+func (s *series) between(since, until time.Time) []Point {
+	var out []Point
+	in := func(p Point) bool {
+		return !p.Time.Before(since) && (until.IsZero() || p.Time.Before(until))
+	}
+	if s.Full {
+		for _, p := range s.Points[s.Head:] {
+			if in(p) {
+				out = append(out, p)
+			}
+		}
+	}
+	for _, p := range s.Points[:s.Head] {
+		if in(p) {
+			out = append(out, p)
+		}
+	}
+	if s.Samples > 0 && in(s.Current) {
+		out = append(out, s.Current)
+	}
+	return out
+}
+
+// Store keeps the resource usage history for a single server at two resolutions,
+// one sample per second for the last hour and one per minute for the last week.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	fine   *series
+	coarse *series
+
+	// Set when samples have been added since the history was last saved, so
+	// that an unchanged history is not rewritten to the disk.
+	dirty bool
+
+	// Set once the history has been removed so that it is not written back to
+	// the disk again.
+	removed bool
+}
+
+// New returns a store that is persisted to the given path, loading any existing
+// history from it.
+func New(p string) (*Store, error) {
+	s := &Store{
+		path:   p,
+		fine:   newSeries(time.Second, time.Hour),
+		coarse: newSeries(time.Minute, time.Hour*24*7),
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.Wrap(err, "history: failed to open history file")
+	}
+	defer f.Close()
+	var d struct{ Fine, Coarse *series }
+	if err := gob.NewDecoder(f).Decode(&d); err != nil {
+		// A corrupt history file is not worth failing over, just start again.
+		return s, nil
+	}
+	if d.Fine != nil && len(d.Fine.Points) == len(s.fine.Points) {
+		s.fine = d.Fine
+	}
+	if d.Coarse != nil && len(d.Coarse.Points) == len(s.coarse.Points) {
+		s.coarse = d.Coarse
+	}
+	return s, nil
+}
+
+// Path returns the path of the file the history is persisted to.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Add records a sample of resource usage at the given time.
+func (s *Store) Add(t time.Time, p Point) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fine.add(t, p)
+	s.coarse.add(t, p)
+	s.dirty = true
+}
+
+// Query returns the resource usage between the two times, oldest first. If the
+// range starts within the last hour per-second points are returned, otherwise
+// per-minute points are returned. The resolution of the points is returned with
+// them.
+func (s *Store) Query(since, until time.Time) ([]Point, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sr := s.coarse
+	if !since.IsZero() && !since.Before(time.Now().Add(-time.Hour)) {
+		sr = s.fine
+	}
+	return sr.between(since, until), sr.Resolution
+}
+
+// Save writes the history to the disk. The file is written to a temporary path
+// first and then moved into place so a crash cannot leave a partial file. Nothing
+// is written if no samples have been added since the last save.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.removed || !s.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return errors.WithStack(err)
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrap(err, "history: failed to open history file")
+	}
+	if err := gob.NewEncoder(f).Encode(struct{ Fine, Coarse *series }{s.fine, s.coarse}); err != nil {
+		f.Close()
+		return errors.Wrap(err, "history: failed to encode history")
+	}
+	if err := f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return errors.WithStack(err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// Remove deletes the history from the disk. Any later calls to Save are ignored.
+func (s *Store) Remove() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removed = true
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
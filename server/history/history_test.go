@@ -0,0 +1,91 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesAggregation(t *testing.T) {
+	s := newSeries(time.Minute, time.Minute*3)
+	base := time.Now().Truncate(time.Minute)
+
+	s.add(base, Point{CpuAbsolute: 10, Memory: 100, Disk: 1})
+	s.add(base.Add(30*time.Second), Point{CpuAbsolute: 30, Memory: 300, Disk: 2})
+	s.add(base.Add(time.Minute), Point{CpuAbsolute: 50, Memory: 500, Disk: 3})
+
+	out := s.between(base, time.Time{})
+	require.Len(t, out, 2)
+	assert.Equal(t, base, out[0].Time)
+	assert.Equal(t, 20.0, out[0].CpuAbsolute)
+	assert.Equal(t, uint64(200), out[0].Memory)
+	assert.Equal(t, int64(2), out[0].Disk)
+
+	// The interval still being aggregated is included as the newest point.
+	assert.Equal(t, 50.0, out[1].CpuAbsolute)
+}
+
+func TestSeriesWraps(t *testing.T) {
+	s := newSeries(time.Minute, time.Minute*3)
+	base := time.Now().Truncate(time.Minute)
+	for i := 0; i < 6; i++ {
+		s.add(base.Add(time.Duration(i)*time.Minute), Point{CpuAbsolute: float64(i)})
+	}
+
+	out := s.between(time.Time{}, time.Time{})
+	require.Len(t, out, 4)
+	for i, p := range out {
+		assert.Equal(t, float64(i+2), p.CpuAbsolute)
+	}
+	assert.Equal(t, base.Add(2*time.Minute), out[0].Time)
+}
+
+func TestStorePersistence(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "stats", "server.gob")
+	s, err := New(p)
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i := 5; i > 0; i-- {
+		s.Add(now.Add(-time.Duration(i)*time.Second), Point{CpuAbsolute: float64(i)})
+	}
+	require.NoError(t, s.Save())
+
+	s, err = New(p)
+	require.NoError(t, err)
+	out, res := s.Query(now.Add(-time.Minute), time.Time{})
+	assert.Equal(t, time.Second, res)
+	assert.Len(t, out, 5)
+
+	_, res = s.Query(now.Add(-2*time.Hour), time.Time{})
+	assert.Equal(t, time.Minute, res)
+}
+
+func TestStoreSaveSkipsUnchanged(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "server.gob")
+	s, err := New(p)
+	require.NoError(t, err)
+
+	// Nothing is written until there is something to save.
+	require.NoError(t, s.Save())
+	_, err = os.Stat(p)
+	assert.True(t, os.IsNotExist(err))
+
+	s.Add(time.Now(), Point{CpuAbsolute: 1})
+	require.NoError(t, s.Save())
+	require.FileExists(t, p)
+
+	// The file is not rewritten while no new samples have been added.
+	require.NoError(t, os.Remove(p))
+	require.NoError(t, s.Save())
+	_, err = os.Stat(p)
+	assert.True(t, os.IsNotExist(err))
+
+	s.Add(time.Now(), Point{CpuAbsolute: 2})
+	require.NoError(t, s.Save())
+	assert.FileExists(t, p)
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// The files that the users and groups on the host, and the subordinate IDs
+// assigned to them, are read from.
+var (
+	hostUsersPath  = "/etc/passwd"
+	hostGroupsPath = "/etc/group"
+	subuidPath     = "/etc/subuid"
+	subgidPath     = "/etc/subgid"
+)
+
+// idAllocator assigns each server a unique user and group ID from the range
+// defined in the configuration when user isolation is enabled. Assignments are
+// persisted to the disk so that a server keeps the same ID, and therefore keeps
+// ownership of its files, across restarts of Wings.
+type idAllocator struct {
+	mu      sync.Mutex
+	loaded  bool
+	ids     map[string]int
+	mapping idMapping
+}
+
+func (a *idAllocator) load() error {
+	if a.loaded {
+		return nil
+	}
+	if u := config.Get().System.UserIsolation.RemapUser; u != "" {
+		m, err := loadIdMapping(u)
+		if err != nil {
+			return err
+		}
+		a.mapping = m
+	}
+	a.ids = make(map[string]int)
+	b, err := ioutil.ReadFile(config.Get().System.GetUserIdsPath())
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "server: could not read assigned user ids")
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &a.ids); err != nil {
+			return errors.Wrap(err, "server: could not parse assigned user ids")
+		}
+	}
+	a.loaded = true
+	return nil
+}
+
+func (a *idAllocator) save() error {
+	b, err := json.Marshal(a.ids)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	p := config.Get().System.GetUserIdsPath()
+	if err := ioutil.WriteFile(p+".tmp", b, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(p+".tmp", p))
+}
+
+// get returns the user the server runs as inside of its container, assigning
+// the lowest unused ID in the range if it does not have one yet, along with the
+// user that owns the files for the server on the host. IDs that belong to an
+// existing user or group on the host are never assigned.
+func (a *idAllocator) get(uuid string) (environment.User, environment.User, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.load(); err != nil {
+		return environment.User{}, environment.User{}, err
+	}
+
+	r := config.Get().System.UserIsolation
+	if id, ok := a.ids[uuid]; ok && id >= r.Start && id < r.Start+r.Size {
+		if uid, gid, ok := a.mapping.host(id); ok {
+			return environment.User{Uid: id, Gid: id}, environment.User{Uid: uid, Gid: gid}, nil
+		}
+	}
+
+	users, err := hostIds(hostUsersPath)
+	if err != nil {
+		return environment.User{}, environment.User{}, err
+	}
+	groups, err := hostIds(hostGroupsPath)
+	if err != nil {
+		return environment.User{}, environment.User{}, err
+	}
+	used := make(map[int]bool, len(a.ids))
+	for _, id := range a.ids {
+		used[id] = true
+	}
+	for id := r.Start; id < r.Start+r.Size; id++ {
+		if used[id] {
+			continue
+		}
+		uid, gid, ok := a.mapping.host(id)
+		if !ok || users[uid] || groups[gid] {
+			continue
+		}
+		a.ids[uuid] = id
+		return environment.User{Uid: id, Gid: id}, environment.User{Uid: uid, Gid: gid}, a.save()
+	}
+	return environment.User{}, environment.User{}, errors.New("server: no user ids remaining in the configured isolation range")
+}
+
+// release removes the ID assigned to a server so that it can be reused.
+func (a *idAllocator) release(uuid string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.load(); err != nil {
+		return
+	}
+	if _, ok := a.ids[uuid]; !ok {
+		return
+	}
+	delete(a.ids, uuid)
+	_ = a.save()
+}
+
+// hostIds returns the IDs of the users or groups in a passwd or group file.
+func hostIds(path string) (map[int]bool, error) {
+	ids := make(map[int]bool)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ids, nil
+		}
+		return nil, errors.Wrap(err, "server: could not read host users")
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		parts := strings.Split(line, ":")
+		if len(parts) < 3 {
+			continue
+		}
+		if id, err := strconv.Atoi(parts[2]); err == nil {
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
+
+type idRange struct {
+	start int
+	size  int
+}
+
+// idMapping translates the IDs used inside of containers to the IDs on the host
+// when Docker is remapping user namespaces. An empty mapping leaves IDs as is.
+type idMapping struct {
+	uids []idRange
+	gids []idRange
+}
+
+// loadIdMapping loads the subordinate user and group ranges for the user that
+// Docker remaps container user namespaces to.
+func loadIdMapping(user string) (idMapping, error) {
+	uids, err := subordinateRanges(subuidPath, user)
+	if err != nil {
+		return idMapping{}, err
+	}
+	gids, err := subordinateRanges(subgidPath, user)
+	if err != nil {
+		return idMapping{}, err
+	}
+	if len(uids) == 0 || len(gids) == 0 {
+		return idMapping{}, errors.New("server: no subordinate ids are assigned to the remap user " + user)
+	}
+	return idMapping{uids: uids, gids: gids}, nil
+}
+
+// host returns the user and group on the host that an ID inside of a container
+// maps to. False is returned if the ID is outside of the subordinate ranges.
+func (m idMapping) host(id int) (int, int, bool) {
+	if m.uids == nil {
+		return id, id, true
+	}
+	uid, ok := mapId(m.uids, id)
+	if !ok {
+		return 0, 0, false
+	}
+	gid, ok := mapId(m.gids, id)
+	if !ok {
+		return 0, 0, false
+	}
+	return uid, gid, true
+}
+
+// mapId maps an ID into the ranges given. Docker combines all of the ranges for
+// the remap user into one namespace in the order they are listed.
+func mapId(ranges []idRange, id int) (int, bool) {
+	for _, r := range ranges {
+		if id < r.size {
+			return r.start + id, true
+		}
+		id -= r.size
+	}
+	return 0, false
+}
+
+// subordinateRanges returns the ranges in a subuid or subgid file assigned to
+// the user, which may be listed by either name or ID.
+func subordinateRanges(path string, name string) ([]idRange, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "server: could not read subordinate ids")
+	}
+	uid := name
+	if u, err := user.Lookup(name); err == nil {
+		uid = u.Uid
+	}
+	var ranges []idRange
+	for _, line := range strings.Split(string(b), "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ":")
+		if len(parts) != 3 || (parts[0] != name && parts[0] != uid) {
+			continue
+		}
+		start, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		size, err := strconv.Atoi(parts[2])
+		if err != nil || size <= 0 {
+			continue
+		}
+		ranges = append(ranges, idRange{start: start, size: size})
+	}
+	return ranges, nil
+}
+
+// User returns the user the server process runs as when user isolation is
+// enabled. If isolation is disabled an empty user is returned and the shared
+// Pterodactyl user is used instead.
+func (s *Server) User() environment.User {
+	s.RLock()
+	defer s.RUnlock()
+	return s.user
+}
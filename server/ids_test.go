@@ -0,0 +1,104 @@
+package server
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+// setIsolationConfig points the allocator at a temporary root directory and
+// host user files, restoring everything once the test is complete.
+func setIsolationConfig(t *testing.T, start int, size int, remap string) string {
+	dir := t.TempDir()
+	orig := config.Get()
+	config.Update(func(c *config.Configuration) {
+		c.System.RootDirectory = dir
+		c.System.UserIsolation.Start = start
+		c.System.UserIsolation.Size = size
+		c.System.UserIsolation.RemapUser = remap
+	})
+	paths := []*string{&hostUsersPath, &hostGroupsPath, &subuidPath, &subgidPath}
+	saved := make([]string, len(paths))
+	for i, p := range paths {
+		saved[i] = *p
+		*p = filepath.Join(dir, filepath.Base(*p))
+	}
+	t.Cleanup(func() {
+		config.Set(orig)
+		for i, p := range paths {
+			*p = saved[i]
+		}
+	})
+	return dir
+}
+
+func TestIdAllocator(t *testing.T) {
+	dir := setIsolationConfig(t, 1000, 3, "")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "passwd"), []byte("root:x:0:0::/root:/bin/sh\nbob:x:1000:1000::/home/bob:/bin/sh\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "group"), []byte("root:x:0:\nstaff:x:1002:\n"), 0644))
+
+	var a idAllocator
+	// IDs belonging to a user or group on the host are skipped.
+	u, owner, err := a.get("one")
+	require.NoError(t, err)
+	assert.Equal(t, environment.User{Uid: 1001, Gid: 1001}, u)
+	assert.Equal(t, u, owner)
+
+	_, _, err = a.get("two")
+	assert.Error(t, err)
+
+	// The same ID is returned for a server, including after the assignments are
+	// loaded again from the disk.
+	u, _, err = a.get("one")
+	require.NoError(t, err)
+	assert.Equal(t, 1001, u.Uid)
+	var b idAllocator
+	u, _, err = b.get("one")
+	require.NoError(t, err)
+	assert.Equal(t, 1001, u.Uid)
+
+	// A released ID can be assigned to another server.
+	b.release("one")
+	u, _, err = b.get("two")
+	require.NoError(t, err)
+	assert.Equal(t, 1001, u.Uid)
+}
+
+func TestIdAllocatorRemapsToSubordinateIds(t *testing.T) {
+	dir := setIsolationConfig(t, 10, 100, "dockremap")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "subuid"), []byte("other:100000:65536\ndockremap:200000:15\ndockremap:300000:65536\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "subgid"), []byte("dockremap:400000:65536\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "passwd"), []byte("taken:x:200010:200010::/:/bin/false\n"), 0644))
+
+	var a idAllocator
+	u, owner, err := a.get("one")
+	require.NoError(t, err)
+	assert.Equal(t, environment.User{Uid: 11, Gid: 11}, u)
+	assert.Equal(t, environment.User{Uid: 200011, Gid: 400011}, owner)
+
+	// The ranges for the user are combined in the order they are listed.
+	for i := 0; i < 4; i++ {
+		_, _, err = a.get(string(rune('a' + i)))
+		require.NoError(t, err)
+	}
+	u, owner, err = a.get("two")
+	require.NoError(t, err)
+	assert.Equal(t, environment.User{Uid: 16, Gid: 16}, u)
+	assert.Equal(t, environment.User{Uid: 300001, Gid: 400016}, owner)
+}
+
+func TestIdAllocatorRequiresSubordinateIds(t *testing.T) {
+	dir := setIsolationConfig(t, 10, 100, "dockremap")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "subuid"), []byte("other:100000:65536\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "subgid"), []byte("other:100000:65536\n"), 0644))
+
+	var a idAllocator
+	_, _, err := a.get("one")
+	assert.Error(t, err)
+}
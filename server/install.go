@@ -4,12 +4,15 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"html/template"
 	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/apex/log"
@@ -20,7 +23,9 @@ import (
 
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/environment/docker"
 	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/installcache"
 	"github.com/pterodactyl/wings/system"
 )
 
@@ -30,6 +35,10 @@ import (
 // Pass true as the first argument in order to execute a server sync before the process to
 // ensure the latest information is used.
 func (s *Server) Install(sync bool) error {
+	return s.install(sync, false)
+}
+
+func (s *Server) install(sync bool, reinstall bool) error {
 	if sync {
 		s.Log().Info("syncing server state with remote source before executing installation process")
 		if err := s.Sync(); err != nil {
@@ -38,19 +47,37 @@ func (s *Server) Install(sync bool) error {
 	}
 
 	var err error
+	var rolledBack bool
 	if !s.Config().SkipEggScripts {
 		// Send the start event so the Panel can automatically update. We don't send this unless the process
 		// is actually going to run, otherwise all sorts of weird rapid UI behavior happens since there isn't
 		// an actual install process being executed.
 		s.Events().Publish(InstallStartedEvent, "")
 
-		err = s.internalInstall()
+		var snap *installSnapshot
+		if reinstall && config.Get().System.SnapshotBeforeReinstall {
+			snap, err = s.snapshotBeforeInstall()
+		}
+		if err == nil {
+			err = s.internalInstall(snap != nil)
+			if snap != nil {
+				if err != nil {
+					rolledBack = snap.restore(s)
+				}
+				snap.remove(s)
+			}
+		}
 	} else {
 		s.Log().Info("server configured to skip running installation scripts for this egg, not executing process")
 	}
 
 	s.Log().WithField("was_successful", err == nil).Debug("notifying panel of server install state")
-	if serr := s.SyncInstallState(err == nil); serr != nil {
+	req := remote.InstallStatusRequest{Successful: err == nil, Reinstall: reinstall, RolledBack: rolledBack}
+	if err != nil {
+		req.Error = err.Error()
+		req.Canceled = errors.Is(err, ErrInstallCanceled)
+	}
+	if serr := s.SyncInstallState(req); serr != nil {
 		l := s.Log().WithField("was_successful", err == nil)
 
 		// If the request was successful but there was an error with this request, attach the
@@ -76,6 +103,7 @@ func (s *Server) Install(sync bool) error {
 
 // Reinstalls a server's software by utilizing the install script for the server egg. This
 // does not touch any existing files for the server, other than what the script modifies.
+// If enabled, a snapshot of the files is taken first and restored if the script fails.
 func (s *Server) Reinstall() error {
 	if s.Environment.State() != environment.ProcessOfflineState {
 		s.Log().Debug("waiting for server instance to enter a stopped state")
@@ -84,11 +112,11 @@ func (s *Server) Reinstall() error {
 		}
 	}
 
-	return s.Install(true)
+	return s.install(true, true)
 }
 
 // Internal installation function used to simplify reporting back to the Panel.
-func (s *Server) internalInstall() error {
+func (s *Server) internalInstall(rollback bool) error {
 	script, err := s.client.GetInstallationScript(s.Context(), s.ID())
 	if err != nil {
 		return err
@@ -97,6 +125,7 @@ func (s *Server) internalInstall() error {
 	if err != nil {
 		return err
 	}
+	p.rollback = rollback
 
 	s.Log().Info("beginning installation process for server")
 	if err := p.Run(); err != nil {
@@ -107,12 +136,66 @@ func (s *Server) internalInstall() error {
 	return nil
 }
 
+// ErrInstallDiskLimit is returned when the installation script is stopped for
+// using more disk space than the server is allowed.
+var ErrInstallDiskLimit = errors.Sentinel("install: installation exceeded the disk space limit for the server")
+
+// ErrInstallCanceled is returned when the installation process is canceled
+// before the script finishes running.
+var ErrInstallCanceled = errors.Sentinel("install: installation process was canceled")
+
+// ErrNotInstalling is returned when attempting to cancel the installation
+// process for a server that is not running one.
+var ErrNotInstalling = errors.Sentinel("install: server is not running an installation process")
+
 type InstallationProcess struct {
 	Server *Server
 	Script *remote.InstallationScript
 
 	client  *client.Client
 	context context.Context
+	// The context for the current run of the process, canceled when the
+	// installation is canceled. The server context is still used for cleaning
+	// up after the container so that it can happen once this is canceled.
+	runCtx context.Context
+	// The reference the installation container is created from once the image has
+	// been pulled, which is the verified digest if the image requires verification.
+	image string
+	// Set when a snapshot of the server files was taken before this process runs,
+	// in which case a non-zero exit code from the script fails the installation so
+	// that the snapshot is restored.
+	rollback bool
+}
+
+// InstallProgress is a progress marker emitted by an installation script by
+// writing a line such as "##progress 40 Downloading server jar" to its output.
+type InstallProgress struct {
+	Percent int    `json:"percent"`
+	Message string `json:"message"`
+}
+
+// parseInstallProgress returns the progress marker in a line of output from the
+// installation script, if the line is one.
+func parseInstallProgress(line string) (InstallProgress, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "##progress ") {
+		return InstallProgress{}, false
+	}
+	parts := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "##progress ")), " ", 2)
+	pct, err := strconv.Atoi(strings.TrimSuffix(parts[0], "%"))
+	if err != nil {
+		return InstallProgress{}, false
+	}
+	if pct < 0 {
+		pct = 0
+	} else if pct > 100 {
+		pct = 100
+	}
+	p := InstallProgress{Percent: pct}
+	if len(parts) > 1 {
+		p.Message = strings.TrimSpace(parts[1])
+	}
+	return p, true
 }
 
 // Generates a new installation process struct that will be used to create containers,
@@ -128,6 +211,7 @@ func NewInstallationProcess(s *Server, script *remote.InstallationScript) (*Inst
 	} else {
 		proc.client = c
 		proc.context = s.Context()
+		proc.runCtx = proc.context
 	}
 
 	return proc, nil
@@ -139,6 +223,45 @@ func (s *Server) IsInstalling() bool {
 	return s.installing.Load()
 }
 
+// CancelInstall cancels the installation process that is running for the server.
+// The process stops the installation container and releases the installation
+// lock in the background, and the Panel is then notified that it was canceled.
+func (s *Server) CancelInstall() error {
+	s.RLock()
+	cancel := s.installCancel
+	installing := s.IsInstalling()
+	s.RUnlock()
+	if cancel == nil || !installing {
+		return ErrNotInstalling
+	}
+	s.Log().Info("canceling installation process for server")
+	s.Events().Publish(DaemonMessageEvent, "Canceling installation process...")
+	cancel()
+	return nil
+}
+
+// acquireInstallLock marks the server as installing and stores the function used
+// to cancel the installation. Both are set while holding the server lock so that
+// CancelInstall never sees the server installing without a way to cancel it.
+func (s *Server) acquireInstallLock(cancel context.CancelFunc) bool {
+	s.Lock()
+	defer s.Unlock()
+	if !s.installing.SwapIf(true) {
+		return false
+	}
+	s.installCancel = cancel
+	return true
+}
+
+// releaseInstallLock clears the installation cancel function and marks the server
+// as no longer installing.
+func (s *Server) releaseInstallLock() {
+	s.Lock()
+	defer s.Unlock()
+	s.installCancel = nil
+	s.installing.Store(false)
+}
+
 func (s *Server) IsTransferring() bool {
 	return s.transferring.Load()
 }
@@ -173,8 +296,11 @@ func (ip *InstallationProcess) RemoveContainer() error {
 // Once the container finishes installing the results will be stored in an installation
 // log in the server's configuration directory.
 func (ip *InstallationProcess) Run() error {
+	ctx, cancel := context.WithCancel(ip.context)
+	defer cancel()
+
 	ip.Server.Log().Debug("acquiring installation process lock")
-	if !ip.Server.installing.SwapIf(true) {
+	if !ip.Server.acquireInstallLock(cancel) {
 		return errors.New("install: cannot obtain installation lock")
 	}
 
@@ -183,17 +309,18 @@ func (ip *InstallationProcess) Run() error {
 	// without encountering a wait timeout.
 	defer func() {
 		ip.Server.Log().Debug("releasing installation process lock")
-		ip.Server.installing.Store(false)
+		ip.Server.releaseInstallLock()
 	}()
+	ip.runCtx = ctx
 
 	if err := ip.BeforeExecute(); err != nil {
-		return err
+		return ip.canceledOr(err)
 	}
 
 	cID, err := ip.Execute()
-	if err != nil {
+	if err != nil && cID == "" {
 		_ = ip.RemoveContainer()
-		return err
+		return ip.canceledOr(err)
 	}
 
 	// If this step fails, log a warning but don't exit out of the process. This is completely
@@ -202,9 +329,32 @@ func (ip *InstallationProcess) Run() error {
 		ip.Server.Log().WithField("error", err).Warn("failed to complete after-execute step of installation process")
 	}
 
+	// The script itself failed, the logs for it are still kept above so that the
+	// reason for the failure can be found.
+	if err != nil {
+		return err
+	}
+
+	// The installation script runs as root, so when servers run as their own user
+	// the files it created need to be handed over before the server can use them.
+	if ip.Server.User().Uid != 0 {
+		if err := ip.Server.Filesystem().Chown("/"); err != nil {
+			ip.Server.Log().WithField("error", err).Warn("failed to chown server files after installation")
+		}
+	}
+
 	return nil
 }
 
+// canceledOr returns ErrInstallCanceled if the process was canceled, otherwise
+// the error given is returned.
+func (ip *InstallationProcess) canceledOr(err error) error {
+	if ip.runCtx.Err() != nil && ip.context.Err() == nil {
+		return ErrInstallCanceled
+	}
+	return err
+}
+
 // Returns the location of the temporary data for the installation process.
 func (ip *InstallationProcess) tempDir() string {
 	return filepath.Join(os.TempDir(), "pterodactyl/", ip.Server.ID())
@@ -241,74 +391,14 @@ func (ip *InstallationProcess) writeScriptToDisk() error {
 	return nil
 }
 
-// Pulls the docker image to be used for the installation container.
+// Pulls the docker image to be used for the installation container. The image
+// must be allowed by the image policy for the node just like server images.
 func (ip *InstallationProcess) pullInstallationImage() error {
-	// Get a registry auth configuration from the config.
-	var registryAuth *config.RegistryConfiguration
-	for registry, c := range config.Get().Docker.Registries {
-		if !strings.HasPrefix(ip.Script.ContainerImage, registry) {
-			continue
-		}
-
-		log.WithField("registry", registry).Debug("using authentication for registry")
-		registryAuth = &c
-		break
-	}
-
-	// Get the ImagePullOptions.
-	imagePullOptions := types.ImagePullOptions{All: false}
-	if registryAuth != nil {
-		b64, err := registryAuth.Base64()
-		if err != nil {
-			log.WithError(err).Error("failed to get registry auth credentials")
-		}
-
-		// b64 is a string so if there is an error it will just be empty, not nil.
-		imagePullOptions.RegistryAuth = b64
-	}
-
-	r, err := ip.client.ImagePull(context.Background(), ip.Script.ContainerImage, imagePullOptions)
+	image, err := docker.PullImage(ip.runCtx, ip.Script.ContainerImage, nil)
 	if err != nil {
-		images, ierr := ip.client.ImageList(context.Background(), types.ImageListOptions{})
-		if ierr != nil {
-			// Well damn, something has gone really wrong here, just go ahead and abort there
-			// isn't much anything we can do to try and self-recover from this.
-			return ierr
-		}
-
-		for _, img := range images {
-			for _, t := range img.RepoTags {
-				if t != ip.Script.ContainerImage {
-					continue
-				}
-
-				log.WithFields(log.Fields{
-					"image": ip.Script.ContainerImage,
-					"err":   err.Error(),
-				}).Warn("unable to pull requested image from remote source, however the image exists locally")
-
-				// Okay, we found a matching container image, in that case just go ahead and return
-				// from this function, since there is nothing else we need to do here.
-				return nil
-			}
-		}
-
-		return err
-	}
-	defer r.Close()
-
-	log.WithField("image", ip.Script.ContainerImage).Debug("pulling docker image... this could take a bit of time")
-
-	// Block continuation until the image has been pulled successfully.
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		log.Debug(scanner.Text())
-	}
-
-	if err := scanner.Err(); err != nil {
 		return err
 	}
-
+	ip.image = image
 	return nil
 }
 
@@ -399,8 +489,8 @@ func (ip *InstallationProcess) AfterExecute(containerId string) error {
 func (ip *InstallationProcess) Execute() (string, error) {
 	// Create a child context that is canceled once this function is done running. This
 	// will also be canceled if the parent context (from the Server struct) is canceled
-	// which occurs if the server is deleted.
-	ctx, cancel := context.WithCancel(ip.context)
+	// which occurs if the server is deleted, or if the installation is canceled.
+	ctx, cancel := context.WithCancel(ip.runCtx)
 	defer cancel()
 
 	conf := &container.Config{
@@ -411,50 +501,17 @@ func (ip *InstallationProcess) Execute() (string, error) {
 		OpenStdin:    true,
 		Tty:          true,
 		Cmd:          []string{ip.Script.Entrypoint, "/mnt/install/install.sh"},
-		Image:        ip.Script.ContainerImage,
-		Env:          ip.Server.GetEnvironmentVariables(),
+		Image:        ip.image,
+		Env:          ip.env(),
 		Labels: map[string]string{
 			"Service":       "Pterodactyl",
 			"ContainerType": "server_installer",
 		},
 	}
 
-	tmpfsSize := strconv.Itoa(int(config.Get().Docker.TmpfsSize))
-
-	// rootStr := "C:\\Users\\Administrator\\Ubuntu\\rootfs"
-	// mntPath := rootStr + strings.Replace(ip.Server.Filesystem().Path(), "/", "\\", -1)
-	// tmpDir := rootStr + strings.Replace(ip.tempDir(), "/", "\\", -1)
-
-	hostConf := &container.HostConfig{
-		Mounts: []mount.Mount{
-			{
-				Target:   "/mnt/server",
-				Source:   ip.Server.Filesystem().Path(),
-				Type:     mount.TypeBind,
-				ReadOnly: false,
-			},
-			{
-				Target:   "/mnt/install",
-				Source:   ip.tempDir(),
-				Type:     mount.TypeBind,
-				ReadOnly: false,
-			},
-		},
-		Resources: ip.resourceLimits(),
-		Tmpfs: map[string]string{
-			"/tmp": "rw,exec,nosuid,size=" + tmpfsSize + "M",
-		},
-		DNS: config.Get().Docker.Network.Dns,
-		LogConfig: container.LogConfig{
-			Type: "local",
-			Config: map[string]string{
-				"max-size": "5m",
-				"max-file": "1",
-				"compress": "false",
-			},
-		},
-		Privileged:  true,
-		NetworkMode: container.NetworkMode(config.Get().Docker.Network.Mode),
+	hostConf, err := ip.hostConfig()
+	if err != nil {
+		return "", err
 	}
 
 	// Ensure the root directory for the server exists properly before attempting
@@ -484,6 +541,9 @@ func (ip *InstallationProcess) Execute() (string, error) {
 	if err := ip.client.ContainerStart(ctx, r.ID, types.ContainerStartOptions{}); err != nil {
 		return "", err
 	}
+	if config.Get().Docker.InstallerCache.Enabled {
+		defer ip.allowCacheAccess(ctx, r.ID)()
+	}
 
 	// Process the install event in the background by listening to the stream output until the
 	// container has stopped, at which point we'll disconnect from it.
@@ -497,16 +557,41 @@ func (ip *InstallationProcess) Execute() (string, error) {
 		}
 	}(r.ID)
 
+	// Bind mounts cannot be limited in size by Docker, so the disk usage of the server
+	// is checked while the script runs and the container is killed if it goes over.
+	overLimit := system.NewAtomicBool(false)
+	go ip.watchDiskUsage(ctx, r.ID, overLimit)
+
 	sChan, eChan := ip.client.ContainerWait(ctx, r.ID, container.WaitConditionNotRunning)
 	select {
 	case err := <-eChan:
 		// Once the container has stopped running we can mark the install process as being completed.
 		if err == nil {
 			ip.Server.Events().Publish(DaemonMessageEvent, "Installation process completed.")
+		} else if errors.Is(ip.canceledOr(err), ErrInstallCanceled) {
+			// The container is still running at this point, it is removed along with
+			// the logs being collected once this returns.
+			ip.Server.Events().Publish(DaemonMessageEvent, "Installation process canceled.")
+			return r.ID, ErrInstallCanceled
 		} else {
 			return "", err
 		}
-	case <-sChan:
+	case st := <-sChan:
+		// The container ID is still returned so that the logs for the failed script
+		// can be collected before the container is removed.
+		if overLimit.Load() {
+			return r.ID, ErrInstallDiskLimit
+		}
+		if st.StatusCode != 0 {
+			ip.Server.Events().Publish(DaemonMessageEvent, fmt.Sprintf("Installation script exited with code %d.", st.StatusCode))
+			// Plenty of egg scripts exit with a non-zero code even though they did what
+			// they needed to, so this is only treated as a failure when there is a
+			// snapshot of the previous files to go back to.
+			if ip.rollback {
+				return r.ID, errors.WithDetails(errors.Wrapf(ErrInstallScriptFailed, "exit code %d", st.StatusCode), "exit_code", st.StatusCode)
+			}
+			ip.Server.Log().WithField("exit_code", st.StatusCode).Warn("installation script exited with a non-zero exit code")
+		}
 	}
 
 	return r.ID, nil
@@ -529,6 +614,11 @@ func (ip *InstallationProcess) StreamOutput(ctx context.Context, id string) erro
 
 	evts := ip.Server.Events()
 	err = system.ScanReader(reader, func(line string) {
+		if p, ok := parseInstallProgress(line); ok {
+			b, _ := json.Marshal(p)
+			evts.Publish(InstallProgressEvent, string(b))
+			return
+		}
 		evts.Publish(InstallOutputEvent, line)
 	})
 	if err != nil {
@@ -546,7 +636,7 @@ func (ip *InstallationProcess) StreamOutput(ctx context.Context, id string) erro
 // This also avoids a server with limits such as 4GB of memory from accidentally
 // consuming 2-5x the defined limits during the install process and causing
 // system instability.
-func (ip *InstallationProcess) resourceLimits() container.Resources {
+func (ip *InstallationProcess) resourceLimits() (container.Resources, error) {
 	limits := config.Get().Docker.InstallerLimits
 
 	// Create a copy of the configuration so we're not accidentally making changes
@@ -565,19 +655,165 @@ func (ip *InstallationProcess) resourceLimits() container.Resources {
 		cfg.CpuLimit = limits.Cpu
 	}
 
-	resources := cfg.AsContainerResources()
-	// Explicitly remove the PID limits for the installation container. These scripts are
-	// defined at an administrative level and users can't manually execute things like a
-	// fork bomb during this process.
-	resources.PidsLimit = nil
+	resources, err := cfg.AsContainerResources()
+	if err != nil {
+		return resources, err
+	}
+	// The PID limit for servers is often too low for package managers, so use the
+	// separate limit for installation containers instead.
+	if limits.Pids > 0 {
+		resources.PidsLimit = &limits.Pids
+	} else {
+		resources.PidsLimit = nil
+	}
+
+	return resources, nil
+}
+
+// watchDiskUsage kills the installation container if the files for the server
+// exceed the disk limit for it while the installation script is running.
+func (ip *InstallationProcess) watchDiskUsage(ctx context.Context, id string, overLimit *system.AtomicBool) {
+	if ip.Server.DiskSpace() <= 0 {
+		return
+	}
+	system.Every(ctx, time.Second*10, func(_ time.Time) {
+		if overLimit.Load() || ip.Server.Filesystem().HasSpaceAvailable(false) {
+			return
+		}
+		overLimit.Store(true)
+		ip.Server.Events().Publish(DaemonMessageEvent, "Installation process exceeded the disk space limit for the server, stopping...")
+		if err := ip.client.ContainerKill(ctx, id, "SIGKILL"); err != nil && !client.IsErrNotFound(err) {
+			ip.Server.Log().WithField("error", err).Warn("failed to kill installation container after exceeding disk limit")
+		}
+	})
+}
+
+// env returns the environment variables for the installation container. When the
+// download cache is enabled the container is pointed at it as its HTTP proxy.
+func (ip *InstallationProcess) env() []string {
+	env := ip.Server.GetEnvironmentVariables()
+	if config.Get().Docker.InstallerCache.Enabled {
+		proxy := "http://" + installcache.Address()
+		env = append(env, "HTTP_PROXY="+proxy, "http_proxy="+proxy, "NO_PROXY=localhost,127.0.0.1", "no_proxy=localhost,127.0.0.1")
+	}
+	return env
+}
+
+// allowCacheAccess lets the installation container make requests through the
+// download cache. The returned function removes the access again and must be
+// called once the container has stopped.
+func (ip *InstallationProcess) allowCacheAccess(ctx context.Context, id string) func() {
+	c, err := ip.client.ContainerInspect(ctx, id)
+	if err != nil || c.NetworkSettings == nil {
+		ip.Server.Log().WithField("error", err).Warn("failed to determine installation container address, downloads will not be cached")
+		return func() {}
+	}
+	var ips []string
+	for _, n := range c.NetworkSettings.Networks {
+		for _, addr := range []string{n.IPAddress, n.GlobalIPv6Address} {
+			if addr != "" {
+				ips = append(ips, addr)
+				installcache.Allow(addr)
+			}
+		}
+	}
+	return func() {
+		for _, addr := range ips {
+			installcache.Revoke(addr)
+		}
+	}
+}
+
+// hostConfig returns the host configuration used for the installation container.
+func (ip *InstallationProcess) hostConfig() (*container.HostConfig, error) {
+	tmpfsSize := strconv.Itoa(int(config.Get().Docker.TmpfsSize))
+
+	resources, err := ip.resourceLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	// rootStr := "C:\\Users\\Administrator\\Ubuntu\\rootfs"
+	// mntPath := rootStr + strings.Replace(ip.Server.Filesystem().Path(), "/", "\\", -1)
+	// tmpDir := rootStr + strings.Replace(ip.tempDir(), "/", "\\", -1)
 
-	return resources
+	hostConf := &container.HostConfig{
+		Mounts: []mount.Mount{
+			{
+				Target:   "/mnt/server",
+				Source:   ip.Server.Filesystem().Path(),
+				Type:     mount.TypeBind,
+				ReadOnly: false,
+			},
+			{
+				Target:   "/mnt/install",
+				Source:   ip.tempDir(),
+				Type:     mount.TypeBind,
+				ReadOnly: false,
+			},
+		},
+		Resources: resources,
+		Tmpfs: map[string]string{
+			"/tmp": "rw,exec,nosuid,size=" + tmpfsSize + "M",
+		},
+		DNS: config.Get().Docker.Network.Dns,
+		LogConfig: container.LogConfig{
+			Type: "local",
+			Config: map[string]string{
+				"max-size": "5m",
+				"max-file": "1",
+				"compress": "false",
+			},
+		},
+		NetworkMode: container.NetworkMode(config.Get().Docker.Network.Mode),
+	}
+
+	// Installation scripts are written by the community, so unless the egg has been
+	// explicitly trusted by an administrator they get most of the same restrictions
+	// as the server container itself. The root filesystem is left writable since
+	// most scripts need to install packages before doing anything else.
+	if ip.isPrivileged() {
+		ip.Server.Log().WithField("egg", ip.Server.Config().Egg.ID).Warn("running installation script in a privileged container")
+		hostConf.Privileged = true
+	} else {
+		hostConf.SecurityOpt = []string{"no-new-privileges"}
+		hostConf.CapDrop = installerDroppedCapabilities()
+	}
+
+	return hostConf, nil
+}
+
+// installerDroppedCapabilities returns the capabilities removed from unprivileged
+// installation containers. The script runs as root but the server data directory
+// is owned by the pterodactyl user with a 0700 mode, so root needs to be able to
+// bypass file permissions and take ownership of files to install the server.
+func installerDroppedCapabilities() []string {
+	var out []string
+	for _, c := range docker.DroppedCapabilities {
+		if c == "dac_override" || c == "fowner" || c == "chown" {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// isPrivileged returns true if the egg for the server has been allowed to run
+// its installation script in a privileged container.
+func (ip *InstallationProcess) isPrivileged() bool {
+	id := ip.Server.Config().Egg.ID
+	for _, e := range config.Get().Docker.PrivilegedInstallers {
+		if id != "" && e == id {
+			return true
+		}
+	}
+	return false
 }
 
 // SyncInstallState makes a HTTP request to the Panel instance notifying it that
 // the server has completed the installation process, and what the state of the
-// server is. A boolean value of "true" means everything was successful, "false"
-// means something went wrong and the server must be deleted and re-created.
-func (s *Server) SyncInstallState(successful bool) error {
-	return s.client.SetInstallationStatus(s.Context(), s.ID(), successful)
+// server is. If the installation was not successful the Panel is also told if
+// the files for the server were rolled back to how they were before it started.
+func (s *Server) SyncInstallState(data remote.InstallStatusRequest) error {
+	return s.client.SetInstallationStatus(s.Context(), s.ID(), data)
 }
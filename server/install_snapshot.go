@@ -0,0 +1,233 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"emperror.dev/errors"
+
+	"github.com/pterodactyl/wings/server/backup"
+)
+
+// ErrInstallScriptFailed is returned when the installation script for a server
+// exits with a non-zero exit code.
+var ErrInstallScriptFailed = errors.Sentinel("install: installation script failed")
+
+// installSnapshot is a local archive of the files for a server taken before a
+// reinstall so that they can be restored if the installation script fails.
+type installSnapshot struct {
+	b *backup.LocalBackup
+}
+
+// snapshotBeforeInstall archives the current files for the server into the
+// backup directory. If the server does not have any files yet there is nothing
+// to restore and no snapshot is taken. An error is returned if there is not
+// enough free space in the backup directory for the files of the server.
+func (s *Server) snapshotBeforeInstall() (*installSnapshot, error) {
+	entries, err := ioutil.ReadDir(s.Filesystem().Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "install: could not read server directory for snapshot")
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	b := backup.NewLocal(s.client, "install-"+s.ID(), "")
+	// The archive is compressed, but there is no way to know by how much ahead of
+	// time so require enough space for all of the files as they are.
+	s.Filesystem().HasSpaceAvailable(false)
+	free, err := diskFree(filepath.Dir(b.Path()))
+	if err != nil {
+		return nil, errors.Wrap(err, "install: could not determine free space for snapshot")
+	}
+	if free >= 0 && free < s.Filesystem().CachedUsage() {
+		return nil, errors.New("install: not enough free space in the backup directory to snapshot the server files")
+	}
+
+	s.Events().Publish(DaemonMessageEvent, "Creating a snapshot of the server files before reinstalling...")
+	if err := writeSnapshot(s.Filesystem().Path(), b.Path()); err != nil {
+		_ = b.Remove()
+		return nil, errors.WrapIf(err, "install: could not create snapshot of server files")
+	}
+	return &installSnapshot{b: b}, nil
+}
+
+// writeSnapshot writes every file, directory and symlink in the root directory
+// to a compressed tar archive at dst. Unlike backups, empty directories and the
+// targets of symlinks are kept so that the directory can be restored exactly.
+func writeSnapshot(root string, dst string) error {
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw, _ := gzip.NewWriterLevel(f, gzip.BestSpeed)
+	tw := tar.NewWriter(gw)
+
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		var link string
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		case info.IsDir(), info.Mode().IsRegular():
+		default:
+			// Sockets, devices and the like cannot be restored, so leave them out.
+			return nil
+		}
+		h, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		h.Name = filepath.ToSlash(strings.TrimPrefix(p, root+string(filepath.Separator)))
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tw.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(gw.Close())
+}
+
+// restore replaces the files for the server with the contents of the snapshot.
+// Returns true if the files were restored successfully.
+func (i *installSnapshot) restore(s *Server) bool {
+	s.Events().Publish(DaemonMessageEvent, "Installation failed, restoring the server files from before the reinstall...")
+	s.Log().Warn("installation process failed, restoring server files from snapshot")
+
+	entries, err := ioutil.ReadDir(s.Filesystem().Path())
+	if err != nil {
+		s.Log().WithField("error", err).Error("failed to read server directory for snapshot restore")
+		return false
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(s.Filesystem().Path(), e.Name())); err != nil {
+			s.Log().WithField("error", err).Error("failed to remove files created by installation process")
+			return false
+		}
+	}
+
+	if err := i.extract(s); err != nil {
+		s.Log().WithField("error", err).Error("failed to restore server files from snapshot")
+		return false
+	}
+	if err := s.Filesystem().Chown("/"); err != nil {
+		s.Log().WithField("error", err).Warn("failed to chown server files after restoring snapshot")
+	}
+	// Refresh the disk usage so the files written by the installation process no
+	// longer count against the limit for the server.
+	s.Filesystem().HasSpaceAvailable(false)
+	s.Events().Publish(DaemonMessageEvent, "Server files have been restored.")
+	return true
+}
+
+// extract writes the contents of the snapshot back into the server directory.
+// The directory of every entry is resolved before anything is written so that
+// nothing can end up outside of it, including through a symlink restored earlier.
+func (i *installSnapshot) extract(s *Server) error {
+	root, err := filepath.EvalSymlinks(s.Filesystem().Path())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f, err := os.Open(i.b.Path())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+		p, err := snapshotPath(root, h.Name)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(h.Mode).Perm()
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, mode); err != nil {
+				return errors.WithStack(err)
+			}
+			if err := os.Chmod(p, mode); err != nil {
+				return errors.WithStack(err)
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(h.Linkname, p); err != nil {
+				return errors.WithStack(err)
+			}
+		case tar.TypeReg:
+			w, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			_, err = io.Copy(w, tr)
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+}
+
+// snapshotPath returns the location in the root directory for a file in the
+// snapshot. The parent directories are created by entries earlier in the archive
+// so they must already exist.
+func snapshotPath(root string, name string) (string, error) {
+	p := filepath.Join(root, filepath.Clean("/"+name))
+	dir, err := filepath.EvalSymlinks(filepath.Dir(p))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if dir != root && !strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		return "", errors.New("install: snapshot path resolves outside of the server directory: " + name)
+	}
+	return filepath.Join(dir, filepath.Base(p)), nil
+}
+
+// remove deletes the snapshot archive from the disk.
+func (i *installSnapshot) remove(s *Server) {
+	if err := i.b.Remove(); err != nil && !os.IsNotExist(err) {
+		s.Log().WithField("error", err).Warn("failed to remove installation snapshot")
+	}
+}
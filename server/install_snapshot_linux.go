@@ -0,0 +1,15 @@
+package server
+
+import (
+	"syscall"
+)
+
+// diskFree returns the number of bytes available to unprivileged users on the
+// filesystem the path is on.
+func diskFree(path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
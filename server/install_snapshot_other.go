@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package server
+
+// diskFree returns the number of bytes available on the filesystem the path is
+// on. This is only supported on Linux, elsewhere -1 is returned and the space
+// is not checked.
+func diskFree(path string) (int64, error) {
+	return -1, nil
+}
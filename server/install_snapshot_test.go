@@ -0,0 +1,83 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestInstallSnapshotRestore(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Update(func(c *config.Configuration) {
+		c.System.BackupDirectory = t.TempDir()
+	})
+
+	s := newTestServer(t)
+	root := s.Filesystem().Path()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "plugins", "empty"), 0750))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "plugins", "a.jar"), []byte("jar"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "server.properties"), []byte("motd=hi"), 0644))
+	require.NoError(t, os.Symlink("server.properties", filepath.Join(root, "link")))
+
+	snap, err := s.snapshotBeforeInstall()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	defer snap.remove(s)
+
+	// Simulate an installation script that wiped and replaced the files.
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "plugins")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "server.properties"), []byte("motd=new"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "install.log"), []byte("failed"), 0644))
+
+	require.True(t, snap.restore(s))
+
+	st, err := os.Stat(filepath.Join(root, "plugins", "empty"))
+	require.NoError(t, err)
+	assert.True(t, st.IsDir())
+	assert.Equal(t, os.FileMode(0750), st.Mode().Perm())
+
+	b, err := ioutil.ReadFile(filepath.Join(root, "plugins", "a.jar"))
+	require.NoError(t, err)
+	assert.Equal(t, "jar", string(b))
+	st, err = os.Stat(filepath.Join(root, "plugins", "a.jar"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), st.Mode().Perm())
+
+	b, err = ioutil.ReadFile(filepath.Join(root, "server.properties"))
+	require.NoError(t, err)
+	assert.Equal(t, "motd=hi", string(b))
+
+	target, err := os.Readlink(filepath.Join(root, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "server.properties", target)
+
+	_, err = os.Stat(filepath.Join(root, "install.log"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInstallSnapshotSkipsEmptyServer(t *testing.T) {
+	s := newTestServer(t)
+	snap, err := s.snapshotBeforeInstall()
+	require.NoError(t, err)
+	assert.Nil(t, snap)
+}
+
+func TestSnapshotPathStaysInRoot(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Symlink(t.TempDir(), filepath.Join(root, "escape")))
+
+	p, err := snapshotPath(root, "../../passwd")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "passwd"), p)
+
+	_, err = snapshotPath(root, "escape/file")
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+func TestInstallerHostConfig(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.Egg.ID = "egg"
+	ip := &InstallationProcess{Server: s}
+
+	hc, err := ip.hostConfig()
+	require.NoError(t, err)
+	assert.False(t, hc.Privileged)
+	assert.Contains(t, hc.SecurityOpt, "no-new-privileges")
+	assert.Contains(t, hc.CapDrop, "net_raw")
+	// Root in the container must still be able to work with the server files
+	// which are owned by the pterodactyl user.
+	assert.NotContains(t, hc.CapDrop, "dac_override")
+	assert.NotContains(t, hc.CapDrop, "fowner")
+	assert.NotContains(t, hc.CapDrop, "chown")
+	assert.Equal(t, s.Filesystem().Path(), hc.Mounts[0].Source)
+	assert.Equal(t, "/mnt/server", hc.Mounts[0].Target)
+
+	config.Update(func(c *config.Configuration) {
+		c.Docker.PrivilegedInstallers = []string{"egg"}
+	})
+	defer config.Update(func(c *config.Configuration) {
+		c.Docker.PrivilegedInstallers = nil
+	})
+	hc, err = ip.hostConfig()
+	require.NoError(t, err)
+	assert.True(t, hc.Privileged)
+	assert.Empty(t, hc.CapDrop)
+}
+
+func TestParseInstallProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		ok   bool
+		p    InstallProgress
+	}{
+		{"##progress 40 Downloading server jar", true, InstallProgress{Percent: 40, Message: "Downloading server jar"}},
+		{"##progress 40% Downloading", true, InstallProgress{Percent: 40, Message: "Downloading"}},
+		{"  ##progress 75  ", true, InstallProgress{Percent: 75}},
+		{"##progress 150 Done", true, InstallProgress{Percent: 100, Message: "Done"}},
+		{"##progress -5", true, InstallProgress{Percent: 0}},
+		{"##progress abc", false, InstallProgress{}},
+		{"##progress", false, InstallProgress{}},
+		{"40%", false, InstallProgress{}},
+		{"progress 40", false, InstallProgress{}},
+		{"", false, InstallProgress{}},
+	}
+	for _, tc := range tests {
+		p, ok := parseInstallProgress(tc.line)
+		assert.Equal(t, tc.ok, ok, tc.line)
+		assert.Equal(t, tc.p, p, tc.line)
+	}
+}
+
+func TestServerCancelInstall(t *testing.T) {
+	s := newTestServer(t)
+	assert.ErrorIs(t, s.CancelInstall(), ErrNotInstalling)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.True(t, s.acquireInstallLock(cancel))
+	assert.True(t, s.IsInstalling())
+	// Only a single installation can hold the lock at a time.
+	assert.False(t, s.acquireInstallLock(func() {}))
+
+	require.NoError(t, s.CancelInstall())
+	assert.Error(t, ctx.Err())
+
+	s.releaseInstallLock()
+	assert.False(t, s.IsInstalling())
+	assert.ErrorIs(t, s.CancelInstall(), ErrNotInstalling)
+}
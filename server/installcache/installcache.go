@@ -0,0 +1,565 @@
+// Package installcache implements a caching HTTP proxy for installation
+// containers. Downloads made by installation scripts over plain HTTP are stored
+// on the disk keyed by their URL, and are revalidated against the origin using
+// the validators returned with them so that repeated installs of the same egg
+// across servers on the node do not need to download the same files again.
+//
+// Downloads made over HTTPS do not go through the proxy at all, and since most
+// server software and SteamCMD is downloaded over HTTPS only a small portion of
+// the traffic for a typical installation is cached.
+//
+// The proxy only accepts requests from the addresses of installation containers
+// that are currently running, and never connects to loopback, link-local or
+// private addresses so that it cannot be used to reach services on the host.
+package installcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+)
+
+// Hop-by-hop headers that are only meaningful for a single connection and must
+// not be forwarded by a proxy.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Stats are the counters for the cache since Wings was started.
+type Stats struct {
+	Enabled bool   `json:"enabled"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Size    int64  `json:"size"`
+}
+
+// entry is the metadata stored alongside each cached response body.
+type entry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+	Size         int64  `json:"size"`
+}
+
+// Cache is a caching forward proxy for plain HTTP requests. HTTPS requests are
+// not handled since they cannot be cached without intercepting the connection.
+type Cache struct {
+	dir     string
+	maxSize int64
+	client  *http.Client
+
+	mu   sync.Mutex
+	size int64
+
+	// The addresses of the installation containers allowed to use the proxy,
+	// along with the number of times each one has been allowed.
+	clients map[string]int
+	// Allows connections to private addresses, which is only used by tests since
+	// the test servers listen on the loopback interface.
+	allowPrivate bool
+
+	hits   uint64
+	misses uint64
+}
+
+// New returns a cache storing responses in the given directory, which is created
+// if it does not already exist. Once the total size of the stored responses
+// exceeds maxSize bytes the least recently used responses are removed.
+func New(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "installcache: failed to create cache directory")
+	}
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		clients: make(map[string]int),
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	// Responses are stored exactly as they were sent by the origin, so the client
+	// must not transparently decompress them.
+	t.DisableCompression = true
+	// The destination is checked once the hostname has been resolved so that a
+	// hostname resolving to a private address cannot be used to get around it.
+	t.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			return c.checkDestination(address)
+		},
+	}).DialContext
+	c.client = &http.Client{
+		Transport: t,
+		// Redirects are returned to the installation script which then requests
+		// the new location through the proxy itself.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "installcache: failed to read cache directory")
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".body") {
+			c.size += f.Size()
+		} else if strings.HasPrefix(f.Name(), "tmp-") {
+			// Left behind by downloads that were interrupted when Wings stopped.
+			_ = os.Remove(filepath.Join(dir, f.Name()))
+		}
+	}
+	return c, nil
+}
+
+// Key returns the key that the response for a URL is stored under.
+func Key(url string) string {
+	h := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(h[:])
+}
+
+// Stats returns the hit and miss counts for the cache along with the total size
+// of the responses stored in it.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	size := c.size
+	c.mu.Unlock()
+	return Stats{
+		Enabled: true,
+		Hits:    atomic.LoadUint64(&c.hits),
+		Misses:  atomic.LoadUint64(&c.misses),
+		Size:    size,
+	}
+}
+
+// Allow lets the given address make requests through the proxy. Each call must
+// be matched by a call to Revoke once the container has stopped.
+func (c *Cache) Allow(ip string) {
+	c.mu.Lock()
+	c.clients[ip]++
+	c.mu.Unlock()
+}
+
+// Revoke removes an address allowed by Allow.
+func (c *Cache) Revoke(ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clients[ip] <= 1 {
+		delete(c.clients, ip)
+		return
+	}
+	c.clients[ip]--
+}
+
+// authorized returns true if the remote address of a request belongs to a
+// running installation container. The address of a container is only known
+// once it has started, so requests are given a few seconds for it to be allowed.
+func (c *Cache) authorized(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	for i := 0; i < 50; i++ {
+		c.mu.Lock()
+		ok := c.clients[host] > 0
+		c.mu.Unlock()
+		if ok {
+			return true
+		}
+		select {
+		case <-r.Context().Done():
+			return false
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+	return false
+}
+
+// checkDestination returns an error if the resolved address is one that the
+// proxy must not connect to.
+func (c *Cache) checkDestination(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errors.New("installcache: invalid destination address " + host)
+	}
+	if !c.allowPrivate && blockedIP(ip) {
+		return errors.New("installcache: destination address " + host + " is not allowed")
+	}
+	return nil
+}
+
+var blockedNetworks = func() []*net.IPNet {
+	var out []*net.IPNet
+	for _, n := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+	} {
+		_, ipnet, _ := net.ParseCIDR(n)
+		out = append(out, ipnet)
+	}
+	return out
+}()
+
+// blockedIP returns true if the address is loopback, link-local, private or
+// otherwise not routable on the internet.
+func blockedIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, n := range blockedNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP handles a request made to the proxy. Cacheable responses are served
+// from the disk once the origin confirms they have not changed, or if the origin
+// cannot be reached at all. Everything else is passed through untouched.
+func (c *Cache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !c.authorized(r) {
+		http.Error(w, "requests can only be made through this proxy by installation containers", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodConnect || !r.URL.IsAbs() || r.URL.Scheme != "http" {
+		http.Error(w, "only plain HTTP requests can be made through this proxy", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var key string
+	var cached *entry
+	if cacheableRequest(r) {
+		key = Key(r.URL.String())
+		cached = c.lookup(key)
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	copyHeader(req.Header, r.Header)
+	removeHopHeaders(req.Header)
+	req.ContentLength = r.ContentLength
+	if cached != nil && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	} else {
+		cached = nil
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		if cached != nil {
+			log.WithFields(log.Fields{"url": cached.URL, "error": err}).Debug("installcache: origin unreachable, serving stale response")
+			c.serve(w, key, cached)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+
+	if cached != nil && res.StatusCode == http.StatusNotModified {
+		c.serve(w, key, cached)
+		return
+	}
+	if key != "" {
+		atomic.AddUint64(&c.misses, 1)
+	}
+
+	removeHopHeaders(res.Header)
+	copyHeader(w.Header(), res.Header)
+	w.WriteHeader(res.StatusCode)
+	if key == "" || !c.cacheableResponse(res) {
+		_, _ = io.Copy(w, res.Body)
+		return
+	}
+	if err := c.store(w, key, r.URL.String(), res); err != nil {
+		log.WithFields(log.Fields{"url": r.URL.String(), "error": err}).Debug("installcache: response was not stored")
+	}
+}
+
+// lookup returns the metadata for a stored response, or nil if there is not one.
+func (c *Cache) lookup(key string) *entry {
+	b, err := ioutil.ReadFile(c.path(key, ".json"))
+	if err != nil {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(c.path(key, ".body")); err != nil {
+		return nil
+	}
+	return &e
+}
+
+// serve writes a stored response to the client.
+func (c *Cache) serve(w http.ResponseWriter, key string, e *entry) {
+	f, err := os.Open(c.path(key, ".body"))
+	if err != nil {
+		http.Error(w, "cached response is no longer available", http.StatusBadGateway)
+		return
+	}
+	defer f.Close()
+	atomic.AddUint64(&c.hits, 1)
+
+	// The modification time of the body tracks when it was last used so that the
+	// least recently used responses are removed first.
+	now := time.Now()
+	_ = os.Chtimes(f.Name(), now, now)
+
+	if e.ContentType != "" {
+		w.Header().Set("Content-Type", e.ContentType)
+	}
+	if e.ETag != "" {
+		w.Header().Set("ETag", e.ETag)
+	}
+	if e.LastModified != "" {
+		w.Header().Set("Last-Modified", e.LastModified)
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(e.Size, 10))
+	w.Header().Set("X-Cache", "HIT")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, f)
+}
+
+// store writes the body of the response to the client while also writing it to
+// the disk. The response is only kept if the entire body was received.
+func (c *Cache) store(w io.Writer, key string, url string, res *http.Response) error {
+	tmp, err := ioutil.TempFile(c.dir, "tmp-*")
+	if err != nil {
+		_, _ = io.Copy(w, res.Body)
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(io.MultiWriter(w, tmp), res.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if res.ContentLength >= 0 && n != res.ContentLength {
+		return errors.New("installcache: response body was truncated")
+	}
+	if n > c.maxSize {
+		return errors.New("installcache: response is larger than the cache")
+	}
+
+	b, err := json.Marshal(entry{
+		URL:          url,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		ContentType:  res.Header.Get("Content-Type"),
+		Size:         n,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st, err := os.Stat(c.path(key, ".body")); err == nil {
+		c.size -= st.Size()
+	}
+	if err := os.Rename(tmp.Name(), c.path(key, ".body")); err != nil {
+		return errors.WithStack(err)
+	}
+	c.size += n
+	if err := ioutil.WriteFile(c.path(key, ".json"), b, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+	c.evict(key)
+	return nil
+}
+
+// evict removes the least recently used responses until the total size of the
+// cache is within the limit, other than the response that was just stored under
+// the given key. The caller must hold the lock.
+func (c *Cache) evict(keep string) {
+	if c.size <= c.maxSize {
+		return
+	}
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	var bodies []os.FileInfo
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".body") {
+			bodies = append(bodies, f)
+		}
+	}
+	sort.Slice(bodies, func(i, j int) bool {
+		return bodies[i].ModTime().Before(bodies[j].ModTime())
+	})
+	for _, f := range bodies {
+		if c.size <= c.maxSize {
+			return
+		}
+		key := strings.TrimSuffix(f.Name(), ".body")
+		if key == keep {
+			continue
+		}
+		if err := os.Remove(c.path(key, ".body")); err != nil {
+			continue
+		}
+		_ = os.Remove(c.path(key, ".json"))
+		c.size -= f.Size()
+	}
+}
+
+func (c *Cache) path(key string, ext string) string {
+	return filepath.Join(c.dir, key+ext)
+}
+
+// cacheableRequest returns true if the response to the request could be stored.
+// Requests with credentials or for part of a file are never stored.
+func cacheableRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet && r.Header.Get("Range") == "" && r.Header.Get("Authorization") == ""
+}
+
+// cacheableResponse returns true if the response can be stored. Only responses
+// with a validator are stored since there would otherwise be no way to tell if
+// they are still current without downloading them again.
+func (c *Cache) cacheableResponse(res *http.Response) bool {
+	if res.StatusCode != http.StatusOK || res.ContentLength > c.maxSize {
+		return false
+	}
+	if res.Header.Get("ETag") == "" && res.Header.Get("Last-Modified") == "" {
+		return false
+	}
+	if res.Header.Get("Vary") == "*" || res.Header.Get("Content-Range") != "" {
+		return false
+	}
+	cc := strings.ToLower(res.Header.Get("Cache-Control"))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
+
+func copyHeader(dst http.Header, src http.Header) {
+	for k, v := range src {
+		for _, vv := range v {
+			dst.Add(k, vv)
+		}
+	}
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, f := range h["Connection"] {
+		for _, k := range strings.Split(f, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				h.Del(k)
+			}
+		}
+	}
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+}
+
+var (
+	mu  sync.RWMutex
+	std *Cache
+)
+
+// Serve starts the cache configured for the node and listens for requests from
+// installation containers until the context is canceled.
+func Serve(ctx context.Context) error {
+	cfg := config.Get().Docker.InstallerCache
+	c, err := New(config.Get().System.GetInstallCachePath(), cfg.MaxSize*1024*1024)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	std = c
+	mu.Unlock()
+
+	s := &http.Server{Addr: Address(), Handler: c}
+	go func() {
+		<-ctx.Done()
+		_ = s.Close()
+	}()
+	log.WithField("address", s.Addr).Info("installer download cache is now listening")
+	if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return errors.Wrap(err, "installcache: failed to listen")
+	}
+	return nil
+}
+
+// Address returns the address the cache listens on, which is the gateway of the
+// Docker network so that it can be reached from within the containers.
+func Address() string {
+	c := config.Get().Docker
+	return c.Network.Interface + ":" + strconv.Itoa(c.InstallerCache.Port)
+}
+
+// Allow lets the installation container with the given address use the cache
+// running on the node. This does nothing if the cache is not enabled.
+func Allow(ip string) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if std != nil {
+		std.Allow(ip)
+	}
+}
+
+// Revoke removes an address allowed by Allow from the cache running on the node.
+func Revoke(ip string) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if std != nil {
+		std.Revoke(ip)
+	}
+}
+
+// GetStats returns the stats for the cache running on the node. If the cache is
+// not enabled the zero value is returned.
+func GetStats() Stats {
+	mu.RLock()
+	defer mu.RUnlock()
+	if std == nil {
+		return Stats{}
+	}
+	return std.Stats()
+}
@@ -0,0 +1,152 @@
+package installcache
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, proxy *httptest.Server, u string) (*http.Response, string) {
+	p, _ := url.Parse(proxy.URL)
+	c := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(p)}}
+	res, err := c.Get(u)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(b)
+}
+
+// newTestCache returns a cache that accepts requests from and makes requests to
+// the loopback interface that the test servers listen on.
+func newTestCache(t *testing.T, maxSize int64) *Cache {
+	c, err := New(t.TempDir(), maxSize)
+	require.NoError(t, err)
+	c.allowPrivate = true
+	c.Allow("127.0.0.1")
+	return c
+}
+
+func TestCacheRevalidates(t *testing.T) {
+	var requests, full int
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		_, _ = w.Write([]byte("server.jar contents"))
+	}))
+	defer origin.Close()
+
+	c := newTestCache(t, 1024)
+	proxy := httptest.NewServer(c)
+	defer proxy.Close()
+
+	res, body := get(t, proxy, origin.URL+"/server.jar")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "server.jar contents", body)
+
+	res, body = get(t, proxy, origin.URL+"/server.jar")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "HIT", res.Header.Get("X-Cache"))
+	assert.Equal(t, "server.jar contents", body)
+
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, full)
+	s := c.Stats()
+	assert.Equal(t, uint64(1), s.Hits)
+	assert.Equal(t, uint64(1), s.Misses)
+	assert.Equal(t, int64(len(body)), s.Size)
+
+	// Stale responses are served if the origin goes away.
+	origin.Close()
+	_, body = get(t, proxy, origin.URL+"/server.jar")
+	assert.Equal(t, "server.jar contents", body)
+}
+
+func TestCacheSkipsResponsesWithoutValidators(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("dynamic"))
+	}))
+	defer origin.Close()
+
+	c := newTestCache(t, 1024)
+	proxy := httptest.NewServer(c)
+	defer proxy.Close()
+
+	get(t, proxy, origin.URL+"/")
+	res, _ := get(t, proxy, origin.URL+"/")
+	assert.Empty(t, res.Header.Get("X-Cache"))
+	assert.Equal(t, int64(0), c.Stats().Size)
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		_, _ = w.Write([]byte(strings.Repeat("a", 60)))
+	}))
+	defer origin.Close()
+
+	c := newTestCache(t, 100)
+	proxy := httptest.NewServer(c)
+	defer proxy.Close()
+
+	get(t, proxy, origin.URL+"/one")
+	get(t, proxy, origin.URL+"/two")
+
+	assert.Equal(t, int64(60), c.Stats().Size)
+	assert.Nil(t, c.lookup(Key(origin.URL+"/one")))
+	assert.NotNil(t, c.lookup(Key(origin.URL+"/two")))
+}
+
+func TestCacheRejectsUnknownClients(t *testing.T) {
+	c, err := New(t.TempDir(), 1024)
+	require.NoError(t, err)
+	c.allowPrivate = true
+	proxy := httptest.NewServer(c)
+	defer proxy.Close()
+
+	c.Allow("10.0.0.2")
+	c.Revoke("10.0.0.2")
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	ctx, cancel := context.WithCancel(r.Context())
+	cancel()
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, r.WithContext(ctx))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCacheRejectsPrivateDestinations(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("metadata"))
+	}))
+	defer origin.Close()
+
+	c := newTestCache(t, 1024)
+	c.allowPrivate = false
+	proxy := httptest.NewServer(c)
+	defer proxy.Close()
+
+	res, body := get(t, proxy, origin.URL+"/")
+	assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	assert.NotContains(t, body, "metadata")
+
+	for ip, blocked := range map[string]bool{
+		"127.0.0.1": true, "169.254.169.254": true, "10.1.2.3": true, "172.18.0.1": true,
+		"192.168.1.1": true, "::1": true, "fe80::1": true, "fd00::1": true, "::ffff:127.0.0.1": true,
+		"1.1.1.1": false, "2606:4700:4700::1111": false,
+	} {
+		assert.Equal(t, blocked, blockedIP(net.ParseIP(ip)), ip)
+	}
+}
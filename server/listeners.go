@@ -2,9 +2,9 @@ package server
 
 import (
 	"encoding/json"
-	"regexp"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/apex/log"
 
@@ -12,6 +12,8 @@ import (
 	"github.com/pterodactyl/wings/environment"
 	"github.com/pterodactyl/wings/events"
 	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/history"
+	"github.com/pterodactyl/wings/system"
 )
 
 var dockerEvents = []string{
@@ -118,6 +120,17 @@ func (s *Server) StartEventListeners() {
 		s.resources.Stats = st
 		s.resources.mu.Unlock()
 
+		if h := s.StatsHistory(); h != nil {
+			h.Add(time.Now(), history.Point{
+				CpuAbsolute: st.CpuAbsolute,
+				Memory:      st.Memory,
+				MemoryLimit: st.MemoryLimit,
+				RxBytes:     st.Network.RxBytes,
+				TxBytes:     st.Network.TxBytes,
+				Disk:        s.Filesystem().CachedUsage(),
+			})
+		}
+
 		// If there is no disk space available at this point, trigger the server disk limiter logic
 		// which will start to stop the running instance.
 		if !s.Filesystem().HasSpaceAvailable(true) {
@@ -146,8 +159,6 @@ func (s *Server) StartEventListeners() {
 	}
 }
 
-var stripAnsiRegex = regexp.MustCompile("[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZcf-ntqry=><~]))")
-
 // Custom listener for console output events that will check if the given line
 // of output matches one that should mark the server as started or not.
 func (s *Server) onConsoleOutput(data string) {
@@ -159,7 +170,7 @@ func (s *Server) onConsoleOutput(data string) {
 		// Check if we should strip ansi color codes.
 		if processConfiguration.Startup.StripAnsi {
 			// Strip ansi color codes from the data string.
-			data = stripAnsiRegex.ReplaceAllString(data, "")
+			data = system.StripAnsiRegex.ReplaceAllString(data, "")
 		}
 
 		// Iterate over all the done lines.
@@ -191,4 +202,7 @@ func (s *Server) onConsoleOutput(data string) {
 			s.Environment.SetState(environment.ProcessOfflineState)
 		}
 	}
+
+	// Check the line of output against any console triggers defined for the server.
+	s.checkTriggers(data)
 }
@@ -24,9 +24,11 @@ import (
 )
 
 type Manager struct {
-	mu      sync.RWMutex
-	client  remote.Client
-	servers []*Server
+	mu          sync.RWMutex
+	client      remote.Client
+	servers     []*Server
+	allocations *allocationIndex
+	ids         idAllocator
 }
 
 // NewManager returns a new server manager instance. This will boot up all the
@@ -44,7 +46,7 @@ func NewManager(ctx context.Context, client remote.Client) (*Manager, error) {
 // loading any of the servers from the disk. This allows the caller to set their
 // own servers into the collection as needed.
 func NewEmptyManager(client remote.Client) *Manager {
-	return &Manager{client: client}
+	return &Manager{client: client, allocations: newAllocationIndex()}
 }
 
 // Client returns the HTTP client interface that allows interaction with the
@@ -111,6 +113,8 @@ func (m *Manager) Find(filter func(match *Server) bool) *Server {
 }
 
 // Remove removes all items from the collection that match the filter function.
+// The ports assigned to the removed servers are released so that they can be
+// assigned to another server.
 func (m *Manager) Remove(filter func(match *Server) bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -118,11 +122,24 @@ func (m *Manager) Remove(filter func(match *Server) bool) {
 	for _, v := range m.servers {
 		if !filter(v) {
 			r = append(r, v)
+		} else {
+			m.allocations.release(v.ID())
+			m.ids.release(v.ID())
 		}
 	}
 	m.servers = r
 }
 
+// ApplyConfiguration updates the values the servers and the Panel client keep a
+// copy of once the configuration has been reloaded.
+func (m *Manager) ApplyConfiguration() {
+	cfg := config.Get()
+	m.client.SetCredentials(cfg.AuthenticationTokenId, cfg.AuthenticationToken)
+	for _, s := range m.All() {
+		s.Throttler().SetConfiguration(cfg.Throttles)
+	}
+}
+
 // PersistStates writes the current environment states to the disk for each
 // server. This is generally called at a specific interval defined in the root
 // runner command to avoid hammering disk I/O when tons of server switch states
@@ -132,6 +149,9 @@ func (m *Manager) PersistStates() error {
 	states := map[string]string{}
 	for _, s := range m.All() {
 		states[s.ID()] = s.Environment.State()
+		if s.Hibernator().IsHibernating() {
+			states[s.ID()] = HibernatingState
+		}
 	}
 	data, err := json.Marshal(states)
 	if err != nil {
@@ -172,11 +192,23 @@ func (m *Manager) InitServer(data remote.ServerConfigurationResponse) (*Server,
 	if err != nil {
 		return nil, err
 	}
+	// The ports for the server are reserved when the configuration is first loaded
+	// so that a server conflicting with one that is already loaded is refused.
+	s.allocations = m.allocations
 	if err := s.UpdateDataStructure(data.Settings); err != nil {
 		return nil, err
 	}
 
 	s.fs = filesystem.New(filepath.Join(config.Get().System.Data, s.ID()), s.DiskSpace(), s.Config().Egg.FileDenylist)
+	if config.Get().System.UserIsolation.Enabled {
+		u, owner, err := m.ids.get(s.ID())
+		if err != nil {
+			m.allocations.release(s.ID())
+			return nil, err
+		}
+		s.user = u
+		s.fs.SetOwner(owner.Uid, owner.Gid)
+	}
 
 	// Right now we only support a Docker based environment, so I'm going to hard code
 	// this logic in. When we're ready to support other environment we'll need to make
@@ -185,6 +217,9 @@ func (m *Manager) InitServer(data remote.ServerConfigurationResponse) (*Server,
 		Mounts:      s.Mounts(),
 		Allocations: s.cfg.Allocations,
 		Limits:      s.cfg.Build,
+		Network:     s.cfg.Network,
+		Options:     s.ContainerOptions(),
+		User:        s.user,
 	}
 
 	envCfg := environment.NewConfiguration(settings, s.GetEnvironmentVariables())
@@ -193,15 +228,26 @@ func (m *Manager) InitServer(data remote.ServerConfigurationResponse) (*Server,
 	}
 
 	if env, err := docker.New(s.ID(), &meta, envCfg); err != nil {
+		m.allocations.release(s.ID())
 		return nil, err
 	} else {
 		s.Environment = env
 		s.StartEventListeners()
 		s.Throttler().StartTimer(s.Context())
+		s.Scheduler().StartTimer(s.Context())
+		s.StartQueryTimer(s.Context())
+		s.Hibernator().StartTimer(s.Context())
+		if err := s.startStatsHistory(); err != nil {
+			s.Log().WithField("error", err).Warn("failed to load resource usage history for server")
+		}
+		if err := s.startConsoleLog(); err != nil {
+			s.Log().WithField("error", err).Warn("failed to open persistent console log for server")
+		}
 	}
 
 	// Forces the configuration to be synced with the panel.
 	if err := s.SyncWithConfiguration(data); err != nil {
+		m.allocations.release(s.ID())
 		return nil, err
 	}
 
@@ -219,10 +265,19 @@ func (m *Manager) init(ctx context.Context) error {
 	log.Info("fetching list of servers from API")
 	servers, err := m.client.GetServers(ctx, config.Get().RemoteQuery.BootServersPerPage)
 	if err != nil {
-		if !remote.IsRequestError(err) {
-			return errors.WithStackIf(err)
+		// If the Panel cannot be reached boot from the configurations that were
+		// cached the last time it was, so that a Panel outage does not also take
+		// down every server on the node when it reboots.
+		cached, cerr := m.loadCachedConfigurations(ctx, err)
+		if cerr != nil {
+			if !remote.IsRequestError(err) {
+				return errors.WithStackIf(err)
+			}
+			return errors.WrapIf(err, "manager: failed to retrieve server configurations")
 		}
-		return errors.WrapIf(err, "manager: failed to retrieve server configurations")
+		servers = cached
+	} else {
+		cacheConfigurations(servers)
 	}
 
 	start := time.Now()
@@ -236,11 +291,9 @@ func (m *Manager) init(ctx context.Context) error {
 			// Parse the json.RawMessage into an expected struct value. We do this here so that a single broken
 			// server does not cause the entire boot process to hang, and allows us to show more useful error
 			// messaging in the output.
-			d := remote.ServerConfigurationResponse{
-				Settings: data.Settings,
-			}
 			log.WithField("server", data.Uuid).Info("creating new server object from API response")
-			if err := json.Unmarshal(data.ProcessConfiguration, &d.ProcessConfiguration); err != nil {
+			d, err := parseServerData(data)
+			if err != nil {
 				log.WithField("server", data.Uuid).WithField("error", err).Error("failed to parse server configuration from API response, skipping...")
 				return
 			}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pterodactyl/wings/environment"
+)
+
+var (
+	metricServerLabels = []string{"server"}
+
+	metricServerCpu         = prometheus.NewDesc("wings_server_cpu_absolute", "Absolute CPU usage of the server process.", metricServerLabels, nil)
+	metricServerMemory      = prometheus.NewDesc("wings_server_memory_bytes", "Memory used by the server process in bytes.", metricServerLabels, nil)
+	metricServerMemoryLimit = prometheus.NewDesc("wings_server_memory_limit_bytes", "Memory limit of the server process in bytes.", metricServerLabels, nil)
+	metricServerRx          = prometheus.NewDesc("wings_server_network_rx_bytes", "Bytes received by the server process.", metricServerLabels, nil)
+	metricServerTx          = prometheus.NewDesc("wings_server_network_tx_bytes", "Bytes transmitted by the server process.", metricServerLabels, nil)
+	metricServerDisk        = prometheus.NewDesc("wings_server_disk_bytes", "Disk space used by the server in bytes.", metricServerLabels, nil)
+	metricServerState       = prometheus.NewDesc("wings_server_state", "Current state of the server, the series for the current state has a value of 1.", []string{"server", "state"}, nil)
+	metricServerWebsockets  = prometheus.NewDesc("wings_server_websockets", "Number of websocket connections open for the server.", metricServerLabels, nil)
+)
+
+var metricStates = []string{
+	environment.ProcessOfflineState,
+	environment.ProcessStartingState,
+	environment.ProcessRunningState,
+	environment.ProcessStoppingState,
+}
+
+// managerCollector exports the resource usage of every server on the instance
+// each time the metrics are collected.
+type managerCollector struct {
+	m *Manager
+}
+
+// Collector returns a Prometheus collector exporting the resource usage and
+// state of all the servers in the manager.
+func (m *Manager) Collector() prometheus.Collector {
+	return &managerCollector{m: m}
+}
+
+func (mc *managerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metricServerCpu
+	ch <- metricServerMemory
+	ch <- metricServerMemoryLimit
+	ch <- metricServerRx
+	ch <- metricServerTx
+	ch <- metricServerDisk
+	ch <- metricServerState
+	ch <- metricServerWebsockets
+}
+
+func (mc *managerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range mc.m.All() {
+		if s.Environment == nil {
+			continue
+		}
+		id := s.ID()
+		s.resources.mu.RLock()
+		st := s.resources.Stats
+		s.resources.mu.RUnlock()
+		ch <- prometheus.MustNewConstMetric(metricServerCpu, prometheus.GaugeValue, st.CpuAbsolute, id)
+		ch <- prometheus.MustNewConstMetric(metricServerMemory, prometheus.GaugeValue, float64(st.Memory), id)
+		ch <- prometheus.MustNewConstMetric(metricServerMemoryLimit, prometheus.GaugeValue, float64(st.MemoryLimit), id)
+		ch <- prometheus.MustNewConstMetric(metricServerRx, prometheus.CounterValue, float64(st.Network.RxBytes), id)
+		ch <- prometheus.MustNewConstMetric(metricServerTx, prometheus.CounterValue, float64(st.Network.TxBytes), id)
+		ch <- prometheus.MustNewConstMetric(metricServerDisk, prometheus.GaugeValue, float64(s.Filesystem().CachedUsage()), id)
+		ch <- prometheus.MustNewConstMetric(metricServerWebsockets, prometheus.GaugeValue, float64(s.Websockets().Len()), id)
+
+		state := s.Environment.State()
+		for _, st := range metricStates {
+			v := 0.0
+			if st == state {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(metricServerState, prometheus.GaugeValue, v, id, st)
+		}
+	}
+}
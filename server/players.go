@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/server/query"
+	"github.com/pterodactyl/wings/system"
+)
+
+// QueryConfiguration defines how Wings should query a server to determine the
+// number of players connected to it.
+type QueryConfiguration struct {
+	// The query protocol spoken by the server. If empty the server is never queried.
+	Protocol string `json:"protocol"`
+
+	// The port to send queries to. If not set the port of the default allocation
+	// for the server is used.
+	Port int `json:"port"`
+}
+
+// QueryAddress returns the address that queries for the server should be sent
+// to. Servers bound to all interfaces are queried over the loopback address.
+func (s *Server) QueryAddress() string {
+	c := s.Config()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ip := c.Allocations.DefaultMapping.Ip
+	if ip == "" || ip == "0.0.0.0" {
+		ip = "127.0.0.1"
+	} else if ip == "::" {
+		ip = "::1"
+	}
+	port := c.Allocations.DefaultMapping.Port
+	if c.Query.Port > 0 {
+		port = c.Query.Port
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
+// QueryProtocol returns the protocol used to query the server for the number of
+// connected players, or an empty string if the server should not be queried.
+func (s *Server) QueryProtocol() string {
+	s.cfg.mu.RLock()
+	defer s.cfg.mu.RUnlock()
+	return s.cfg.Query.Protocol
+}
+
+// Players returns the players connected to the server as reported by the last
+// successful query, or nil if that is not known.
+func (s *Server) Players() *query.Players {
+	s.resources.mu.RLock()
+	defer s.resources.mu.RUnlock()
+	return s.resources.Players
+}
+
+// StartQueryTimer begins querying the server in the background for the number of
+// connected players while it is running. The result is stored on the resource
+// usage for the server and sent along with the next stats event.
+func (s *Server) StartQueryTimer(ctx context.Context) {
+	interval := time.Duration(config.Get().System.QueryInterval) * time.Second
+	if interval <= 0 {
+		return
+	}
+	system.Every(ctx, interval, func(_ time.Time) {
+		protocol := s.QueryProtocol()
+		if protocol == "" || !s.IsRunning() {
+			return
+		}
+		p, err := query.Query(ctx, protocol, s.QueryAddress())
+		if err != nil {
+			s.Log().WithField("error", err).Debug("failed to query server for player information")
+			p = nil
+		}
+		// Don't store a result if the server was stopped while the query was in flight,
+		// otherwise the value cleared when it stopped would be replaced.
+		if !s.IsRunning() {
+			p = nil
+		}
+		s.resources.mu.Lock()
+		s.resources.Players = p
+		s.resources.mu.Unlock()
+	})
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerQueryConfiguration(t *testing.T) {
+	s := newTestServer(t)
+	assert.Empty(t, s.QueryProtocol())
+	assert.Equal(t, "127.0.0.1:0", s.QueryAddress())
+
+	s.cfg.Query = QueryConfiguration{Protocol: "minecraft", Port: 25566}
+	s.cfg.Allocations.DefaultMapping.Ip = "::"
+	s.cfg.Allocations.DefaultMapping.Port = 25565
+	assert.Equal(t, "minecraft", s.QueryProtocol())
+	assert.Equal(t, "[::1]:25566", s.QueryAddress())
+
+	// Removing the protocol from the server stops it from being queried.
+	require.NoError(t, s.UpdateDataStructure([]byte(`{"query":{}}`)))
+	assert.Empty(t, s.QueryProtocol())
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/pterodactyl/wings/config"
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/remote"
 )
 
 type PowerAction string
@@ -162,9 +163,20 @@ func (s *Server) HandlePowerAction(action PowerAction, waitSeconds ...int) error
 // Execute a few functions before actually calling the environment start commands. This ensures
 // that everything is ready to go for environment booting, and that the server can even be started.
 func (s *Server) onBeforeStart() error {
+	// If the server is hibernating stop listening on the allocation so that the port
+	// is free for the server process.
+	s.Hibernator().release()
+
 	s.Log().Info("syncing server configuration with panel")
 	if err := s.Sync(); err != nil {
-		return errors.WithMessage(err, "unable to sync server data from Panel instance")
+		// Servers are still allowed to start using the last configuration returned
+		// by the Panel if it is down, otherwise an outage would prevent servers from
+		// coming back after the node is rebooted.
+		if !config.Get().RemoteQuery.CacheConfigurations || !remote.IsPanelUnavailable(err) || s.ProcessConfiguration() == nil {
+			return errors.WithMessage(err, "unable to sync server data from Panel instance")
+		}
+		s.Log().WithField("error", err).Warn("panel is unreachable, starting server using the last known configuration")
+		s.PublishConsoleOutputFromDaemon("Panel could not be reached, using the last known server configuration.")
 	}
 
 	// Disallow start & restart if the server is suspended. Do this check after performing a sync
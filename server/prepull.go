@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment/docker"
+	"github.com/pterodactyl/wings/system"
+)
+
+// StartImagePrePuller begins refreshing the images used by the servers on the
+// node once a day during the off-peak hours defined in the configuration. The
+// progress of each pull is published to every server using the image.
+func (m *Manager) StartImagePrePuller(ctx context.Context) {
+	// The hours in the configuration are in the system timezone rather than the
+	// local time of the process, which is not always the same thing.
+	loc, err := time.LoadLocation(config.Get().System.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	var last time.Time
+	system.Every(ctx, time.Minute, func(t time.Time) {
+		t = t.In(loc)
+		c := config.Get().Docker.Images.PrePull
+		if !c.Enabled || !inHourWindow(t.Hour(), c.StartHour, c.EndHour) {
+			return
+		}
+		if last.YearDay() == t.YearDay() && last.Year() == t.Year() {
+			return
+		}
+		last = t
+		m.prePullImages(ctx)
+	})
+}
+
+// inHourWindow returns true if the hour is within the window, allowing for the
+// window to wrap around midnight.
+func inHourWindow(hour int, start int, end int) bool {
+	if start <= end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+func (m *Manager) prePullImages(ctx context.Context) {
+	images := make(map[string][]*docker.Environment)
+	for _, s := range m.All() {
+		image := s.Config().Container.Image
+		e, ok := s.Environment.(*docker.Environment)
+		if !ok || image == "" || strings.HasPrefix(image, "~") {
+			continue
+		}
+		images[image] = append(images[image], e)
+	}
+
+	log.WithField("images", len(images)).Info("pre-pulling container images used by servers")
+	for image, envs := range images {
+		if ctx.Err() != nil {
+			return
+		}
+		pctx, cancel := context.WithTimeout(ctx, time.Minute*15)
+		if _, err := docker.PullImage(pctx, image, envs); err != nil {
+			log.WithField("image", image).WithField("error", err).Warn("failed to pre-pull container image")
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,70 @@
+package query
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+
+	"emperror.dev/errors"
+)
+
+// The session ID sent with each GameSpy4 request. Only the lower four bits of
+// each byte are used by Minecraft servers, so keep it within that range.
+var gamespy4Session = []byte{0x01, 0x02, 0x03, 0x04}
+
+// gamespy4 sends a basic stat request to a server using the GameSpy4 (UT3)
+// query protocol, which is also used by the Minecraft query interface.
+//
+// @see https://wiki.vg/Query
+func gamespy4(conn net.Conn) (*Players, error) {
+	buf := make([]byte, 1400)
+
+	// Request a challenge token from the server.
+	if _, err := conn.Write(append([]byte{0xFE, 0xFD, 0x09}, gamespy4Session...)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b := buf[:n]
+	if len(b) < 6 || b[0] != 0x09 || !bytes.Equal(b[1:5], gamespy4Session) {
+		return nil, ErrInvalidResponse
+	}
+	token, err := strconv.ParseInt(string(bytes.TrimRight(b[5:], "\x00")), 10, 32)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+
+	req := append([]byte{0xFE, 0xFD, 0x00}, gamespy4Session...)
+	req = append(req, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(req[len(req)-4:], uint32(token))
+	if _, err := conn.Write(req); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	n, err = conn.Read(buf)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b = buf[:n]
+	if len(b) < 5 || b[0] != 0x00 || !bytes.Equal(b[1:5], gamespy4Session) {
+		return nil, ErrInvalidResponse
+	}
+
+	// The basic stat response is the MOTD, game type, map, player count and max
+	// player count as null terminated strings.
+	fields := bytes.SplitN(b[5:], []byte{0x00}, 6)
+	if len(fields) < 6 {
+		return nil, ErrInvalidResponse
+	}
+	online, err := strconv.Atoi(string(fields[3]))
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+	max, err := strconv.Atoi(string(fields[4]))
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+	return &Players{Online: online, Max: max}, nil
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+
+	"emperror.dev/errors"
+)
+
+// The maximum size of a status response that will be read from a server.
+const minecraftMaxPacket = 1024 * 1024
+
+// minecraft performs a Server List Ping against a Minecraft: Java Edition server.
+//
+// @see https://wiki.vg/Server_List_Ping
+func minecraft(conn net.Conn) (*Players, error) {
+	host, port, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	p, _ := strconv.Atoi(port)
+
+	// Handshake packet with a protocol version of -1 and the next state set to
+	// status, followed by an empty status request packet.
+	var hs bytes.Buffer
+	writeVarInt(&hs, 0x00)
+	writeVarInt(&hs, -1)
+	writeVarInt(&hs, len(host))
+	hs.WriteString(host)
+	_ = binary.Write(&hs, binary.BigEndian, uint16(p))
+	writeVarInt(&hs, 1)
+
+	var buf bytes.Buffer
+	writeVarInt(&buf, hs.Len())
+	buf.Write(hs.Bytes())
+	writeVarInt(&buf, 1)
+	buf.WriteByte(0x00)
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	r := bufio.NewReader(conn)
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 || length > minecraftMaxPacket {
+		return nil, ErrInvalidResponse
+	}
+	pkt := make([]byte, length)
+	if _, err := io.ReadFull(r, pkt); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pr := bytes.NewReader(pkt)
+	if id, err := readVarInt(pr); err != nil || id != 0x00 {
+		return nil, ErrInvalidResponse
+	}
+	n, err := readVarInt(pr)
+	if err != nil || n < 0 || n > pr.Len() {
+		return nil, ErrInvalidResponse
+	}
+	body := make([]byte, n)
+	_, _ = io.ReadFull(pr, body)
+
+	var status struct {
+		Players struct {
+			Online int `json:"online"`
+			Max    int `json:"max"`
+		} `json:"players"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+	return &Players{Online: status.Players.Online, Max: status.Players.Max}, nil
+}
+
+func writeVarInt(w *bytes.Buffer, v int) {
+	u := uint32(v)
+	for {
+		if u&^0x7f == 0 {
+			w.WriteByte(byte(u))
+			return
+		}
+		w.WriteByte(byte(u&0x7f | 0x80))
+		u >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int, error) {
+	var v uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		v |= uint32(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return int(int32(v)), nil
+		}
+	}
+	return 0, ErrInvalidResponse
+}
@@ -0,0 +1,76 @@
+// Package query implements the common game server query protocols used to
+// determine the number of players connected to a server.
+package query
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"emperror.dev/errors"
+)
+
+// The query protocols that are supported.
+const (
+	ProtocolMinecraft = "minecraft"
+	ProtocolSource    = "source"
+	ProtocolGameSpy4  = "gamespy4"
+)
+
+var (
+	ErrInvalidProtocol = errors.Sentinel("query: invalid protocol")
+	ErrInvalidResponse = errors.Sentinel("query: invalid response from server")
+)
+
+// Players is the result of querying a server.
+type Players struct {
+	Online int `json:"online"`
+	Max    int `json:"max"`
+
+	// The round trip time of the query in milliseconds.
+	Latency int64 `json:"latency"`
+}
+
+// Query sends a query to the server at the given address using the protocol
+// provided and returns the player information for it. If the context does not
+// have a deadline a default timeout of five seconds is applied.
+func Query(ctx context.Context, protocol string, address string) (*Players, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+	}
+
+	var fn func(net.Conn) (*Players, error)
+	network := "udp"
+	switch protocol {
+	case ProtocolMinecraft:
+		fn, network = minecraft, "tcp"
+	case ProtocolSource:
+		fn = source
+	case ProtocolGameSpy4:
+		fn = gamespy4
+	default:
+		return nil, errors.Wrap(ErrInvalidProtocol, "query: "+protocol)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, errors.Wrap(err, "query: failed to connect to server")
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(dl); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	start := time.Now()
+	p, err := fn(conn)
+	if err != nil {
+		return nil, err
+	}
+	p.Latency = time.Since(start).Milliseconds()
+	return p, nil
+}
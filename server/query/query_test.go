@@ -0,0 +1,123 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// udpResponder starts a fake UDP server that passes each packet it receives to
+// the handler and sends back whatever the handler returns.
+func udpResponder(t *testing.T, fn func([]byte) []byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1400)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if res := fn(buf[:n]); res != nil {
+				_, _ = conn.WriteTo(res, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestMinecraft(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		// Read the handshake and status request packets.
+		for i := 0; i < 2; i++ {
+			n, err := readVarInt(r)
+			if err != nil {
+				return
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+				return
+			}
+		}
+		body := `{"version":{"name":"1.17.1","protocol":756},"players":{"max":20,"online":3},"description":{"text":"A Minecraft Server"}}`
+		var pkt bytes.Buffer
+		writeVarInt(&pkt, 0x00)
+		writeVarInt(&pkt, len(body))
+		pkt.WriteString(body)
+		var out bytes.Buffer
+		writeVarInt(&out, pkt.Len())
+		out.Write(pkt.Bytes())
+		_, _ = conn.Write(out.Bytes())
+	}()
+
+	p, err := Query(context.Background(), ProtocolMinecraft, l.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, 3, p.Online)
+	assert.Equal(t, 20, p.Max)
+}
+
+func TestSource(t *testing.T) {
+	challenge := []byte{0x0A, 0x0B, 0x0C, 0x0D}
+	addr := udpResponder(t, func(b []byte) []byte {
+		if !bytes.HasPrefix(b, sourceRequest) {
+			return nil
+		}
+		// Require the challenge to be sent back before responding with the info.
+		if !bytes.Equal(b[len(sourceRequest):], challenge) {
+			return append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 'A'}, challenge...)
+		}
+		res := []byte{0xFF, 0xFF, 0xFF, 0xFF, 'I', 0x11}
+		res = append(res, "Server\x00de_dust2\x00csgo\x00Counter-Strike\x00"...)
+		res = append(res, 0xDA, 0x02, 12, 24, 0x00)
+		return res
+	})
+
+	p, err := Query(context.Background(), ProtocolSource, addr)
+	require.NoError(t, err)
+	assert.Equal(t, 12, p.Online)
+	assert.Equal(t, 24, p.Max)
+}
+
+func TestGameSpy4(t *testing.T) {
+	addr := udpResponder(t, func(b []byte) []byte {
+		if len(b) < 7 || b[0] != 0xFE || b[1] != 0xFD {
+			return nil
+		}
+		switch b[2] {
+		case 0x09:
+			return append(append([]byte{0x09}, b[3:7]...), "9513307\x00"...)
+		case 0x00:
+			if !bytes.Equal(b[7:11], []byte{0x00, 0x91, 0x29, 0x5B}) {
+				return nil
+			}
+			res := append([]byte{0x00}, b[3:7]...)
+			return append(res, "A Minecraft Server\x00SMP\x00world\x005\x0010\x00\xDD\x63127.0.0.1\x00"...)
+		}
+		return nil
+	})
+
+	p, err := Query(context.Background(), ProtocolGameSpy4, addr)
+	require.NoError(t, err)
+	assert.Equal(t, 5, p.Online)
+	assert.Equal(t, 10, p.Max)
+}
+
+func TestInvalidProtocol(t *testing.T) {
+	_, err := Query(context.Background(), "unknown", "127.0.0.1:25565")
+	assert.ErrorIs(t, err, ErrInvalidProtocol)
+}
@@ -0,0 +1,67 @@
+package query
+
+import (
+	"bytes"
+	"net"
+
+	"emperror.dev/errors"
+)
+
+var sourceRequest = append([]byte("\xFF\xFF\xFF\xFFTSource Engine Query"), 0x00)
+
+// source sends an A2S_INFO query to a server using the Source engine query
+// protocol, handling the challenge response sent by newer servers.
+//
+// @see https://developer.valvesoftware.com/wiki/Server_queries#A2S_INFO
+func source(conn net.Conn) (*Players, error) {
+	req := sourceRequest
+	buf := make([]byte, 1400)
+	for i := 0; i < 2; i++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		b := buf[:n]
+		if len(b) < 5 || !bytes.Equal(b[:4], []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+			return nil, ErrInvalidResponse
+		}
+		switch b[4] {
+		case 'A':
+			// The server responded with a challenge, the request must be sent again
+			// with the challenge appended to it.
+			if len(b) < 9 {
+				return nil, ErrInvalidResponse
+			}
+			req = append(append([]byte{}, sourceRequest...), b[5:9]...)
+			continue
+		case 'I':
+			return parseSourceInfo(b[5:])
+		}
+		return nil, ErrInvalidResponse
+	}
+	return nil, ErrInvalidResponse
+}
+
+func parseSourceInfo(b []byte) (*Players, error) {
+	// Skip the protocol version byte.
+	if len(b) < 1 {
+		return nil, ErrInvalidResponse
+	}
+	b = b[1:]
+	// Skip the name, map, folder and game strings.
+	for i := 0; i < 4; i++ {
+		idx := bytes.IndexByte(b, 0x00)
+		if idx < 0 {
+			return nil, ErrInvalidResponse
+		}
+		b = b[idx+1:]
+	}
+	// The application ID is a short, followed by the player and max player bytes.
+	if len(b) < 4 {
+		return nil, ErrInvalidResponse
+	}
+	return &Players{Online: int(b[2]), Max: int(b[3])}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"sync/atomic"
 
 	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/server/query"
 	"github.com/pterodactyl/wings/system"
 )
 
@@ -24,6 +25,15 @@ type ResourceUsage struct {
 	// at all times. It is "manually" set whenever server.Proc() is called. This is kind of just a
 	// hacky solution for now to avoid passing events all over the place.
 	Disk int64 `json:"disk_bytes"`
+
+	// The players connected to the server as reported by the last successful query.
+	// This is nil if the server does not have a query protocol configured, is not
+	// running, or has not responded to a query yet.
+	Players *query.Players `json:"players"`
+
+	// Set when the server has been stopped because it was idle and will be started
+	// again when a client connects to it.
+	Hibernating bool `json:"hibernating"`
 }
 
 // Proc returns the current resource usage stats for the server instance. This returns
@@ -34,6 +44,7 @@ func (s *Server) Proc() ResourceUsage {
 	defer s.resources.mu.Unlock()
 	// Store the updated disk usage when requesting process usage.
 	atomic.StoreInt64(&s.resources.Disk, s.Filesystem().CachedUsage())
+	s.resources.Hibernating = s.Hibernator().IsHibernating()
 	//goland:noinspection GoVetCopyLock
 	return s.resources
 }
@@ -46,8 +57,9 @@ func (ru *ResourceUsage) Reset() {
 
 	ru.Memory = 0
 	ru.CpuAbsolute = 0
-	ru.Network.TxBytes = 0
-	ru.Network.RxBytes = 0
+	ru.Network = environment.NetworkStats{}
+	ru.Io = environment.IoStats{}
+	ru.Players = nil
 }
 
 func (s *Server) emitProcUsage() {
@@ -0,0 +1,281 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/metrics"
+	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/backup"
+	"github.com/pterodactyl/wings/system"
+)
+
+// The actions that can be performed by a single task within a schedule.
+const (
+	ScheduleActionCommand = "command"
+	ScheduleActionPower   = "power"
+	ScheduleActionBackup  = "backup"
+)
+
+var ErrScheduleInvalidAction = errors.Sentinel("schedule: invalid task action")
+
+// ScheduleTask is a single step in a schedule. Tasks are executed in order, and
+// each task waits for TimeOffset seconds after the previous one completes before
+// it is run, allowing things like "announce, wait 60 seconds, restart" chains.
+type ScheduleTask struct {
+	Action string `json:"action"`
+
+	// The command to send, the power action to perform, or the ignored files for
+	// a backup depending on the action of the task.
+	Payload string `json:"payload"`
+
+	// The number of seconds to wait after the previous task before running this one.
+	TimeOffset int `json:"time_offset"`
+
+	// If true the remaining tasks in the schedule will still be executed when this
+	// task fails. Otherwise the schedule is aborted at this task.
+	ContinueOnFailure bool `json:"continue_on_failure"`
+
+	// The adapter that backup tasks store the backup with, as configured on the
+	// Panel. A local backup is created if this is not set.
+	Adapter backup.AdapterType `json:"adapter,omitempty"`
+}
+
+// Schedule is a cron based schedule that is executed locally by Wings so that
+// it continues to run even when the Panel is unreachable.
+type Schedule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// A standard five field cron expression. Descriptors such as "@hourly" are
+	// also accepted.
+	Cron string `json:"cron"`
+
+	IsActive bool `json:"is_active"`
+
+	// If true the schedule is skipped entirely when the server is not running.
+	OnlyWhenOnline bool `json:"only_when_online"`
+
+	Tasks []ScheduleTask `json:"tasks"`
+}
+
+// Parse returns the parsed cron schedule for this instance.
+func (sc *Schedule) Parse() (cron.Schedule, error) {
+	c, err := cron.ParseStandard(sc.Cron)
+	if err != nil {
+		return nil, errors.Wrap(err, "schedule: failed to parse cron expression")
+	}
+	return c, nil
+}
+
+// Scheduler runs the schedules assigned to a server. Only one execution of a
+// given schedule may be running at a time, if a schedule is still running when
+// it is next due that run is skipped.
+type Scheduler struct {
+	mu      sync.Mutex
+	server  *Server
+	running map[string]bool
+	last    time.Time
+
+	// The timezone the cron expressions are evaluated in.
+	loc *time.Location
+}
+
+// Scheduler returns the scheduler instance for the server or creates a new one.
+func (s *Server) Scheduler() *Scheduler {
+	s.schedulerOnce.Do(func() {
+		loc, err := time.LoadLocation(config.Get().System.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		s.scheduler = &Scheduler{server: s, running: make(map[string]bool), loc: loc}
+	})
+	return s.scheduler
+}
+
+// StartTimer begins checking the schedules for the server in the background.
+// Schedules are evaluated at minute precision, the timer just runs a bit more
+// often than that so that a tick is never missed due to drift.
+func (sch *Scheduler) StartTimer(ctx context.Context) {
+	system.Every(ctx, time.Second*15, func(t time.Time) {
+		sch.tick(ctx, t)
+	})
+}
+
+// tick checks each schedule for the server and runs any that are due in the
+// current minute.
+func (sch *Scheduler) tick(ctx context.Context, t time.Time) {
+	m := t.In(sch.loc).Truncate(time.Minute)
+
+	sch.mu.Lock()
+	if !m.After(sch.last) {
+		sch.mu.Unlock()
+		return
+	}
+	sch.last = m
+	sch.mu.Unlock()
+
+	for _, schedule := range sch.due(m) {
+		go sch.Run(ctx, schedule)
+	}
+}
+
+// due returns the schedules for the server that should run in the given minute.
+func (sch *Scheduler) due(m time.Time) []Schedule {
+	var out []Schedule
+	for _, schedule := range sch.server.Config().Schedules {
+		if !schedule.IsActive || len(schedule.Tasks) == 0 {
+			continue
+		}
+		c, err := schedule.Parse()
+		if err != nil {
+			sch.server.Log().WithFields(log.Fields{"schedule": schedule.ID, "error": err}).Warn("skipping schedule with invalid cron expression")
+			continue
+		}
+		if !c.Next(m.Add(-time.Second)).Equal(m) {
+			continue
+		}
+		if schedule.OnlyWhenOnline && !sch.server.IsRunning() {
+			sch.server.Log().WithField("schedule", schedule.ID).Debug("skipping schedule execution: server is not running")
+			continue
+		}
+		out = append(out, schedule)
+	}
+	return out
+}
+
+// Run executes all of the tasks for a schedule and then reports the result of
+// the execution back to the Panel. If the Panel cannot be reached the result
+// is logged and discarded, the schedule itself has still run.
+func (sch *Scheduler) Run(ctx context.Context, schedule Schedule) {
+	sch.mu.Lock()
+	if sch.running[schedule.ID] {
+		sch.mu.Unlock()
+		sch.server.Log().WithField("schedule", schedule.ID).Warn("not executing schedule: previous execution is still running")
+		return
+	}
+	sch.running[schedule.ID] = true
+	sch.mu.Unlock()
+
+	defer func() {
+		sch.mu.Lock()
+		delete(sch.running, schedule.ID)
+		sch.mu.Unlock()
+	}()
+
+	l := sch.server.Log().WithField("schedule", schedule.ID)
+	l.Info("executing scheduled tasks for server")
+
+	res := remote.ScheduleStatusRequest{Successful: true, StartedAt: time.Now()}
+	for i, task := range schedule.Tasks {
+		if task.TimeOffset > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second * time.Duration(task.TimeOffset)):
+			}
+		}
+
+		st := sch.runTask(task)
+		res.Tasks = append(res.Tasks, st)
+		if !st.Successful {
+			l.WithFields(log.Fields{"task": i, "action": task.Action, "error": st.Error}).Warn("failed to execute scheduled task")
+			res.Successful = false
+			if !task.ContinueOnFailure {
+				break
+			}
+		}
+	}
+	res.FinishedAt = time.Now()
+
+	if err := sch.server.client.SendScheduleStatus(ctx, sch.server.ID(), schedule.ID, res); err != nil {
+		l.WithField("error", err).Warn("failed to report schedule execution status to panel")
+	}
+}
+
+// runTask performs the action for a single task and returns the result of it.
+func (sch *Scheduler) runTask(task ScheduleTask) remote.ScheduleTaskStatus {
+	st := remote.ScheduleTaskStatus{Action: task.Action}
+
+	var err error
+	switch task.Action {
+	case ScheduleActionCommand:
+		err = sch.server.Environment.SendCommand(task.Payload)
+	case ScheduleActionPower:
+		action := PowerAction(strings.TrimSpace(task.Payload))
+		if !action.IsValid() {
+			err = errors.Wrap(ErrScheduleInvalidAction, "schedule: invalid power action: "+task.Payload)
+		} else {
+			err = sch.server.HandlePowerAction(action, 30)
+		}
+	case ScheduleActionBackup:
+		st.BackupUuid = uuid.New().String()
+		st.BackupAdapter = string(task.Adapter)
+		if st.BackupAdapter == "" {
+			st.BackupAdapter = string(backup.LocalBackupAdapter)
+		}
+		var ad *backup.ArchiveDetails
+		if ad, err = sch.server.scheduledBackup(st.BackupUuid, backup.AdapterType(st.BackupAdapter), task.Payload); err == nil {
+			r := ad.ToRequest(true)
+			st.Backup = &r
+		}
+	default:
+		err = ErrScheduleInvalidAction
+	}
+
+	st.Successful = err == nil
+	if err != nil {
+		st.Error = err.Error()
+	}
+	return st
+}
+
+// scheduledBackup creates a backup of the server using the given adapter. Unlike
+// a backup started by the Panel this does not notify the Panel directly since it
+// has no record of the backup yet, the details are reported along with the
+// schedule results.
+func (s *Server) scheduledBackup(id string, adapter backup.AdapterType, ignore string) (*backup.ArchiveDetails, error) {
+	var b backup.BackupInterface
+	switch adapter {
+	case backup.LocalBackupAdapter:
+		b = backup.NewLocal(s.client, id, ignore)
+	case backup.S3BackupAdapter:
+		b = backup.NewS3(s.client, id, ignore)
+	default:
+		return nil, errors.New("schedule: backup adapter is not valid: " + string(adapter))
+	}
+	if ignore == "" {
+		if i, err := s.getServerwideIgnoredFiles(); err != nil {
+			s.Log().WithField("error", err).Warn("failed to get server-wide ignored files")
+		} else {
+			ignore = i
+		}
+	}
+	b.WithLogContext(map[string]interface{}{"server": s.ID(), "schedule": true})
+
+	start := time.Now()
+	ad, err := b.Generate(s.Context(), s.Filesystem().Path(), ignore)
+	metrics.ObserveDuration(metrics.BackupDuration.WithLabelValues(string(b.Adapter()), strconv.FormatBool(err == nil)), start)
+	if err != nil {
+		return nil, errors.WrapIf(err, "schedule: failed to generate backup")
+	}
+
+	_ = s.Events().PublishJson(BackupCompletedEvent+":"+id, map[string]interface{}{
+		"uuid":          id,
+		"is_successful": true,
+		"checksum":      ad.Checksum,
+		"checksum_type": "sha1",
+		"file_size":     ad.Size,
+	})
+
+	return ad, nil
+}
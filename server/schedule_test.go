@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/environment"
+	"github.com/pterodactyl/wings/remote"
+)
+
+// commandEnvironment records the commands sent to it, failing any that are
+// "fail".
+type commandEnvironment struct {
+	stateEnvironment
+	mu       sync.Mutex
+	commands []string
+	sentAt   []time.Time
+}
+
+func (e *commandEnvironment) SendCommand(c string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.commands = append(e.commands, c)
+	e.sentAt = append(e.sentAt, time.Now())
+	if c == "fail" {
+		return errors.New("command failed")
+	}
+	return nil
+}
+
+// scheduleClient records the schedule results reported to the Panel.
+type scheduleClient struct {
+	remote.Client
+	mu      sync.Mutex
+	results []remote.ScheduleStatusRequest
+}
+
+func (c *scheduleClient) SendScheduleStatus(_ context.Context, _ string, _ string, data remote.ScheduleStatusRequest) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, data)
+	return nil
+}
+
+func (c *scheduleClient) Results() []remote.ScheduleStatusRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]remote.ScheduleStatusRequest{}, c.results...)
+}
+
+func newScheduleServer(t *testing.T) (*Server, *commandEnvironment, *scheduleClient) {
+	s := newTestServer(t)
+	env := &commandEnvironment{stateEnvironment: stateEnvironment{state: environment.ProcessRunningState}}
+	c := &scheduleClient{}
+	s.Environment = env
+	s.client = c
+	return s, env, c
+}
+
+func TestSchedulerDue(t *testing.T) {
+	s, env, _ := newScheduleServer(t)
+	task := []ScheduleTask{{Action: ScheduleActionCommand, Payload: "say hi"}}
+	s.cfg.Schedules = []Schedule{
+		{ID: "every-minute", Cron: "* * * * *", IsActive: true, Tasks: task},
+		{ID: "half-past", Cron: "30 * * * *", IsActive: true, Tasks: task},
+		{ID: "daily", Cron: "@daily", IsActive: true, Tasks: task},
+		{ID: "inactive", Cron: "* * * * *", Tasks: task},
+		{ID: "no-tasks", Cron: "* * * * *", IsActive: true},
+		{ID: "invalid", Cron: "not a cron", IsActive: true, Tasks: task},
+		{ID: "online", Cron: "* * * * *", IsActive: true, OnlyWhenOnline: true, Tasks: task},
+	}
+	sch := s.Scheduler()
+	sch.loc = time.UTC
+
+	ids := func(m time.Time) []string {
+		var out []string
+		for _, sc := range sch.due(m) {
+			out = append(out, sc.ID)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"every-minute", "online"}, ids(time.Date(2021, 1, 1, 10, 5, 0, 0, time.UTC)))
+	assert.Equal(t, []string{"every-minute", "half-past", "online"}, ids(time.Date(2021, 1, 1, 10, 30, 0, 0, time.UTC)))
+	assert.Equal(t, []string{"every-minute", "daily", "online"}, ids(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	env.state = environment.ProcessOfflineState
+	assert.Equal(t, []string{"every-minute"}, ids(time.Date(2021, 1, 1, 10, 5, 0, 0, time.UTC)))
+}
+
+func TestSchedulerTickUsesTimezone(t *testing.T) {
+	s, env, c := newScheduleServer(t)
+	s.cfg.Schedules = []Schedule{
+		{ID: "nine", Cron: "0 9 * * *", IsActive: true, Tasks: []ScheduleTask{{Action: ScheduleActionCommand, Payload: "say hi"}}},
+	}
+	sch := s.Scheduler()
+	sch.loc = time.FixedZone("UTC+2", 2*60*60)
+
+	// 07:00 UTC is 09:00 in the timezone of the scheduler.
+	sch.tick(context.Background(), time.Date(2021, 1, 1, 7, 0, 10, 0, time.UTC))
+	// The same minute is only ever run once.
+	sch.tick(context.Background(), time.Date(2021, 1, 1, 7, 0, 40, 0, time.UTC))
+	// 09:00 UTC is 11:00 in the timezone of the scheduler, so nothing runs.
+	sch.tick(context.Background(), time.Date(2021, 1, 1, 9, 0, 10, 0, time.UTC))
+
+	assert.Eventually(t, func() bool {
+		return len(c.Results()) == 1
+	}, time.Second, time.Millisecond*10)
+	time.Sleep(time.Millisecond * 50)
+	env.mu.Lock()
+	assert.Equal(t, []string{"say hi"}, env.commands)
+	env.mu.Unlock()
+	assert.Len(t, c.Results(), 1)
+}
+
+func TestSchedulerRun(t *testing.T) {
+	s, env, c := newScheduleServer(t)
+	sch := s.Scheduler()
+
+	sch.Run(context.Background(), Schedule{ID: "chain", Tasks: []ScheduleTask{
+		{Action: ScheduleActionCommand, Payload: "first"},
+		{Action: ScheduleActionCommand, Payload: "second", TimeOffset: 1},
+	}})
+	require.Len(t, env.commands, 2)
+	assert.Equal(t, []string{"first", "second"}, env.commands)
+	// Each task waits for its offset after the previous one.
+	assert.GreaterOrEqual(t, int64(env.sentAt[1].Sub(env.sentAt[0])), int64(time.Second))
+	require.Len(t, c.results, 1)
+	assert.True(t, c.results[0].Successful)
+	assert.Len(t, c.results[0].Tasks, 2)
+
+	// A failed task stops the schedule unless it continues on failure.
+	env.commands = nil
+	sch.Run(context.Background(), Schedule{ID: "stop", Tasks: []ScheduleTask{
+		{Action: ScheduleActionCommand, Payload: "fail"},
+		{Action: ScheduleActionCommand, Payload: "never"},
+	}})
+	assert.Equal(t, []string{"fail"}, env.commands)
+	require.Len(t, c.results, 2)
+	assert.False(t, c.results[1].Successful)
+	require.Len(t, c.results[1].Tasks, 1)
+	assert.Equal(t, "command failed", c.results[1].Tasks[0].Error)
+
+	env.commands = nil
+	sch.Run(context.Background(), Schedule{ID: "continue", Tasks: []ScheduleTask{
+		{Action: ScheduleActionCommand, Payload: "fail", ContinueOnFailure: true},
+		{Action: "invalid"},
+		{Action: ScheduleActionCommand, Payload: "never"},
+	}})
+	assert.Equal(t, []string{"fail"}, env.commands)
+	require.Len(t, c.results, 3)
+	assert.False(t, c.results[2].Successful)
+	require.Len(t, c.results[2].Tasks, 2)
+	assert.True(t, c.results[2].Tasks[0].Error != "" && c.results[2].Tasks[1].Error != "")
+
+	// Canceling the context stops the schedule while it is waiting on a task.
+	env.commands = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sch.Run(ctx, Schedule{ID: "canceled", Tasks: []ScheduleTask{
+		{Action: ScheduleActionCommand, Payload: "first"},
+		{Action: ScheduleActionCommand, Payload: "second", TimeOffset: 60},
+	}})
+	assert.Equal(t, []string{"first"}, env.commands)
+	assert.Len(t, c.results, 3)
+}
+
+func TestScheduledBackupRejectsInvalidAdapter(t *testing.T) {
+	s, _, _ := newScheduleServer(t)
+	_, err := s.scheduledBackup("id", "invalid", "")
+	assert.Error(t, err)
+}
@@ -18,7 +18,9 @@ import (
 	"github.com/pterodactyl/wings/environment/docker"
 	"github.com/pterodactyl/wings/events"
 	"github.com/pterodactyl/wings/remote"
+	"github.com/pterodactyl/wings/server/consolelog"
 	"github.com/pterodactyl/wings/server/filesystem"
+	"github.com/pterodactyl/wings/server/history"
 	"github.com/pterodactyl/wings/system"
 )
 
@@ -64,9 +66,34 @@ type Server struct {
 	transferring *system.AtomicBool
 	restoring    *system.AtomicBool
 
+	// Cancels the running installation process, nil when no process is running.
+	installCancel context.CancelFunc
+
 	// The console throttler instance used to control outputs.
 	throttler *ConsoleThrottler
 
+	// The scheduler responsible for running schedules locally for this server.
+	scheduler     *Scheduler
+	schedulerOnce sync.Once
+
+	// The persistent console log for the server, nil if disabled.
+	consoleLog *consolelog.Log
+
+	triggers *triggerRunner
+
+	// The downsampled resource usage history for the server.
+	history *history.Store
+
+	hibernator     *Hibernator
+	hibernatorOnce sync.Once
+
+	// The index of ports assigned to all of the servers on the node, shared with
+	// the manager that loaded the server.
+	allocations *allocationIndex
+
+	// The user the server runs as when user isolation is enabled.
+	user environment.User
+
 	// Tracks open websocket connections for the server.
 	wsBag       *WebsocketBag
 	wsBagLocker sync.Mutex
@@ -83,6 +110,7 @@ func New(client remote.Client) (*Server, error) {
 		installing:   system.NewAtomicBool(false),
 		transferring: system.NewAtomicBool(false),
 		restoring:    system.NewAtomicBool(false),
+		triggers:     newTriggerRunner(),
 	}
 	if err := defaults.Set(&s); err != nil {
 		return nil, errors.Wrap(err, "server: could not set default values for struct")
@@ -167,7 +195,14 @@ func (s *Server) Sync() error {
 		}
 		return errors.WithStackIf(err)
 	}
-	return s.SyncWithConfiguration(cfg)
+	if err := s.SyncWithConfiguration(cfg); err != nil {
+		return err
+	}
+	data := remote.RawServerData{Uuid: s.ID(), Settings: cfg.Settings, ProcessConfiguration: cfg.RawProcessConfiguration}
+	if err := cacheConfiguration(data); err != nil {
+		s.Log().WithField("error", err).Warn("failed to cache server configuration")
+	}
+	return nil
 }
 
 func (s *Server) SyncWithConfiguration(cfg remote.ServerConfigurationResponse) error {
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/server/filesystem"
+)
+
+func TestMain(m *testing.M) {
+	c, err := config.NewAtPath("")
+	if err != nil {
+		panic(err)
+	}
+	c.AuthenticationToken = "abc"
+	config.Set(c)
+	os.Exit(m.Run())
+}
+
+// newTestServer returns a server with a filesystem in a temporary directory
+// that is not connected to the Panel or an environment.
+func newTestServer(t *testing.T) *Server {
+	s, err := New(nil)
+	require.NoError(t, err)
+	s.cfg.Uuid = "uuid"
+	s.fs = filesystem.New(t.TempDir(), 0, nil)
+	return s
+}
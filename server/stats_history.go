@@ -0,0 +1,44 @@
+package server
+
+import (
+	"time"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/server/history"
+	"github.com/pterodactyl/wings/system"
+)
+
+// StatsHistory returns the resource usage history for the server, or nil if it
+// could not be loaded.
+func (s *Server) StatsHistory() *history.Store {
+	s.RLock()
+	defer s.RUnlock()
+	return s.history
+}
+
+// startStatsHistory loads the resource usage history for the server from the
+// disk. The history is written back to the disk every minute and once the server
+// context is canceled.
+func (s *Server) startStatsHistory() error {
+	h, err := history.New(config.Get().System.GetStatsHistoryPath(s.ID()))
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	s.history = h
+	s.Unlock()
+
+	save := func() {
+		if err := h.Save(); err != nil {
+			s.Log().WithField("error", err).Warn("failed to save resource usage history to disk")
+		}
+	}
+	system.Every(s.Context(), time.Minute, func(_ time.Time) {
+		save()
+	})
+	go func() {
+		<-s.Context().Done()
+		save()
+	}()
+	return nil
+}
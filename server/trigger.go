@@ -0,0 +1,329 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/apex/log"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/system"
+)
+
+// The actions that can be performed when a console trigger matches a line of
+// output from the server.
+const (
+	TriggerActionCommand = "command"
+	TriggerActionRestart = "restart"
+	TriggerActionEvent   = "event"
+	TriggerActionWebhook = "webhook"
+)
+
+var ErrTriggerWebhookHost = errors.Sentinel("trigger: webhook host is not allowed")
+
+// Trigger matches lines of console output against a regular expression and
+// performs an action whenever a line matches. Triggers can be defined by the
+// egg as well as on the server itself.
+type Trigger struct {
+	ID string `json:"id"`
+
+	// The regular expression to match against each line of console output. ANSI
+	// escape codes are removed from the line before it is checked.
+	Pattern string `json:"pattern"`
+
+	Action string `json:"action"`
+
+	// The command to send, the name of the event to publish, or the URL to send a
+	// webhook to depending on the action. For commands and events, capture groups
+	// from the pattern can be referenced using "$1" or "${name}".
+	Payload string `json:"payload"`
+
+	// The minimum number of seconds between two executions of the trigger. A cooldown
+	// of at least one second is always applied so that a trigger cannot loop on its
+	// own output.
+	Cooldown int `json:"cooldown"`
+
+	// Where the trigger was defined, either "egg" or "server". Triggers from the egg
+	// and the server may share an ID, so this is used to keep their cooldowns apart.
+	source string
+}
+
+// key returns the identifier used to track the cooldown of the trigger.
+func (t Trigger) key() string {
+	return t.source + ":" + t.ID
+}
+
+// TriggerEventData is the data published over the websocket and sent to webhooks
+// when a trigger fires.
+type TriggerEventData struct {
+	Trigger string   `json:"trigger"`
+	Event   string   `json:"event,omitempty"`
+	Line    string   `json:"line"`
+	Matches []string `json:"matches"`
+}
+
+// The number of lines of console output that can be waiting to be checked
+// against the triggers before further lines are dropped.
+const triggerQueueSize = 256
+
+// triggerRunner tracks the compiled expressions and cooldowns for the triggers
+// on a server.
+type triggerRunner struct {
+	mu       sync.Mutex
+	compiled map[string]*regexp.Regexp
+	last     map[string]time.Time
+
+	// Lines of console output waiting to be checked, consumed by a worker that is
+	// started the first time a line is queued.
+	lines chan string
+	once  sync.Once
+}
+
+func newTriggerRunner() *triggerRunner {
+	return &triggerRunner{
+		compiled: make(map[string]*regexp.Regexp),
+		last:     make(map[string]time.Time),
+		lines:    make(chan string, triggerQueueSize),
+	}
+}
+
+// Triggers returns all of the console triggers for the server, those defined by
+// the egg first followed by those defined on the server itself.
+func (s *Server) Triggers() []Trigger {
+	c := s.Config()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]Trigger, 0, len(c.Egg.Triggers)+len(c.Triggers))
+	for _, t := range c.Egg.Triggers {
+		t.source = "egg"
+		out = append(out, t)
+	}
+	for _, t := range c.Triggers {
+		t.source = "server"
+		out = append(out, t)
+	}
+	return out
+}
+
+// validTriggers returns the triggers that can be used, logging and dropping any
+// that do not have an ID since their cooldown could not be tracked.
+func (s *Server) validTriggers(triggers []Trigger) []Trigger {
+	out := make([]Trigger, 0, len(triggers))
+	for _, t := range triggers {
+		if t.ID == "" {
+			s.Log().WithField("pattern", t.Pattern).Warn("ignoring console trigger without an id")
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// regexp returns the compiled expression for a pattern, caching the result so
+// that each pattern is only compiled once. Invalid patterns are cached as nil so
+// that the error is only logged a single time.
+func (tr *triggerRunner) regexp(s *Server, pattern string) *regexp.Regexp {
+	if r, ok := tr.compiled[pattern]; ok {
+		return r
+	}
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		s.Log().WithFields(log.Fields{"pattern": pattern, "error": err}).Warn("ignoring console trigger with invalid pattern")
+	}
+	tr.compiled[pattern] = r
+	return r
+}
+
+// prune removes the compiled expressions and cooldowns for triggers that are no
+// longer defined on the server so that the caches do not grow forever as the
+// triggers are changed.
+func (tr *triggerRunner) prune(triggers []Trigger) {
+	if len(tr.compiled) <= len(triggers) && len(tr.last) <= len(triggers) {
+		return
+	}
+	patterns := make(map[string]bool, len(triggers))
+	keys := make(map[string]bool, len(triggers))
+	for _, t := range triggers {
+		patterns[t.Pattern] = true
+		keys[t.key()] = true
+	}
+	for p := range tr.compiled {
+		if !patterns[p] {
+			delete(tr.compiled, p)
+		}
+	}
+	for k := range tr.last {
+		if !keys[k] {
+			delete(tr.last, k)
+		}
+	}
+}
+
+// checkTriggers queues the given line of console output to be checked against
+// the triggers for the server. The line is checked by a separate worker so that
+// the console is never blocked, if that worker falls behind the line is dropped.
+func (s *Server) checkTriggers(line string) {
+	if len(s.Triggers()) == 0 {
+		return
+	}
+	tr := s.triggers
+	tr.once.Do(func() {
+		go s.processTriggers(s.Context())
+	})
+	select {
+	case tr.lines <- line:
+	default:
+		s.Log().Debug("console trigger queue is full, skipping line of output")
+	}
+}
+
+// processTriggers checks queued lines of console output against the triggers
+// until the server context is canceled.
+func (s *Server) processTriggers(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-s.triggers.lines:
+			s.matchTriggers(line)
+		}
+	}
+}
+
+// matchTriggers runs the given line of console output against all of the triggers
+// for the server and executes the action for any that match and are not cooling
+// down. Actions are run in the background so that slow actions do not hold up
+// the following lines.
+func (s *Server) matchTriggers(line string) {
+	triggers := s.Triggers()
+	line = system.StripAnsiRegex.ReplaceAllString(line, "")
+
+	tr := s.triggers
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	defer tr.prune(triggers)
+	for _, t := range triggers {
+		if t.ID == "" {
+			continue
+		}
+		r := tr.regexp(s, t.Pattern)
+		if r == nil {
+			continue
+		}
+		m := r.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		cooldown := time.Duration(t.Cooldown) * time.Second
+		if cooldown < time.Second {
+			cooldown = time.Second
+		}
+		if last, ok := tr.last[t.key()]; ok && time.Since(last) < cooldown {
+			continue
+		}
+		tr.last[t.key()] = time.Now()
+
+		payload := string(r.ExpandString(nil, t.Payload, line, m))
+		matches := r.FindStringSubmatch(line)
+		go func(t Trigger) {
+			if err := s.runTrigger(t, payload, line, matches); err != nil {
+				s.Log().WithFields(log.Fields{"trigger": t.ID, "action": t.Action, "error": err}).Warn("failed to execute console trigger")
+			}
+		}(t)
+	}
+}
+
+// runTrigger performs the action for a trigger that has matched a line of output.
+func (s *Server) runTrigger(t Trigger, payload string, line string, matches []string) error {
+	s.Log().WithFields(log.Fields{"trigger": t.ID, "action": t.Action}).Debug("console trigger matched line of output")
+
+	switch t.Action {
+	case TriggerActionCommand:
+		return s.Environment.SendCommand(payload)
+	case TriggerActionRestart:
+		s.PublishConsoleOutputFromDaemon("Server is being restarted by a console trigger.")
+		return s.HandlePowerAction(PowerActionRestart, 30)
+	case TriggerActionEvent:
+		return s.Events().PublishJson(TriggerEvent, TriggerEventData{
+			Trigger: t.ID,
+			Event:   payload,
+			Line:    line,
+			Matches: matches,
+		})
+	case TriggerActionWebhook:
+		// Do not expand capture groups into the URL, otherwise output from the server
+		// could be used to change where the request is sent.
+		return s.sendTriggerWebhook(t.Payload, TriggerEventData{Trigger: t.ID, Line: line, Matches: matches})
+	}
+	return errors.New("trigger: invalid action: " + t.Action)
+}
+
+// sendTriggerWebhook sends the details of a matched trigger to a webhook URL. The
+// host must be a loopback address or be present in the configured allowlist.
+func (s *Server) sendTriggerWebhook(endpoint string, data TriggerEventData) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return errors.Wrap(err, "trigger: invalid webhook url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("trigger: webhook url must use http or https")
+	}
+	if !webhookHostAllowed(u.Hostname()) {
+		return errors.Wrap(ErrTriggerWebhookHost, "trigger: "+u.Hostname())
+	}
+
+	b, err := json.Marshal(struct {
+		Server string `json:"server"`
+		TriggerEventData
+	}{Server: s.ID(), TriggerEventData: data})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctx, cancel := context.WithTimeout(s.Context(), time.Duration(config.Get().System.ConsoleTriggers.WebhookTimeout)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(b))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Pterodactyl Wings/v"+system.Version)
+
+	// Never follow redirects since they could point at a host that is not allowed.
+	c := http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := c.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errors.Errorf("trigger: webhook returned unexpected status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+func webhookHostAllowed(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, h := range config.Get().System.ConsoleTriggers.WebhookAllowlist {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pterodactyl/wings/config"
+	"github.com/pterodactyl/wings/environment"
+)
+
+func newTriggerServer(t *testing.T) (*Server, *commandEnvironment) {
+	s := newTestServer(t)
+	env := &commandEnvironment{stateEnvironment: stateEnvironment{state: environment.ProcessRunningState}}
+	s.Environment = env
+	return s, env
+}
+
+func (e *commandEnvironment) sent() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string{}, e.commands...)
+}
+
+func TestServerMatchTriggers(t *testing.T) {
+	s, env := newTriggerServer(t)
+	s.cfg.Egg.Triggers = []Trigger{
+		{ID: "join", Pattern: `(\w+) joined the game`, Action: TriggerActionCommand, Payload: "say welcome $1"},
+	}
+	s.cfg.Triggers = []Trigger{
+		// The same ID as the egg trigger, which must not share its cooldown.
+		{ID: "join", Pattern: `(?P<player>\w+) joined`, Action: TriggerActionCommand, Payload: "give ${player} bread"},
+		{ID: "invalid", Pattern: `(`, Action: TriggerActionCommand, Payload: "never"},
+	}
+
+	s.matchTriggers("\u001b[33mSteve joined the game\u001b[0m")
+	assert.Eventually(t, func() bool { return len(env.sent()) == 2 }, time.Second, time.Millisecond*10)
+	assert.ElementsMatch(t, []string{"say welcome Steve", "give Steve bread"}, env.sent())
+
+	// Both triggers are now cooling down.
+	s.matchTriggers("Alex joined the game")
+	time.Sleep(time.Millisecond * 50)
+	assert.Len(t, env.sent(), 2)
+}
+
+func TestServerMatchTriggersIgnoresEmptyID(t *testing.T) {
+	s, env := newTriggerServer(t)
+	s.cfg.Triggers = []Trigger{{Pattern: "done", Action: TriggerActionCommand, Payload: "never"}}
+
+	s.matchTriggers("done")
+	time.Sleep(time.Millisecond * 50)
+	assert.Empty(t, env.sent())
+
+	assert.Empty(t, s.validTriggers(s.cfg.Triggers))
+	assert.Len(t, s.validTriggers([]Trigger{{ID: "a"}, {}}), 1)
+}
+
+func TestServerUpdateDataStructureDropsEmptyTriggerID(t *testing.T) {
+	s, _ := newTriggerServer(t)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.UpdateDataStructure([]byte(`{"uuid":"uuid","triggers":[{"pattern":"x","action":"command"},{"id":"a","pattern":"a","action":"command"}],"egg":{"triggers":[{"pattern":"y","action":"command"}]}}`))
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("updating the configuration with an invalid trigger deadlocked")
+	}
+
+	require.Len(t, s.Config().Triggers, 1)
+	assert.Equal(t, "a", s.Config().Triggers[0].ID)
+	assert.Empty(t, s.Config().Egg.Triggers)
+}
+
+func TestTriggerRunnerPrune(t *testing.T) {
+	s, _ := newTriggerServer(t)
+	s.cfg.Triggers = []Trigger{
+		{ID: "a", Pattern: "a", Action: TriggerActionEvent, Payload: "a"},
+		{ID: "b", Pattern: "b", Action: TriggerActionEvent, Payload: "b"},
+	}
+	s.matchTriggers("a b")
+	assert.Len(t, s.triggers.compiled, 2)
+	assert.Len(t, s.triggers.last, 2)
+
+	// Removed triggers are dropped from the caches the next time a line is checked.
+	s.cfg.Triggers = []Trigger{{ID: "c", Pattern: "c", Action: TriggerActionEvent, Payload: "c"}}
+	s.matchTriggers("nothing")
+	assert.Len(t, s.triggers.compiled, 1)
+	assert.Contains(t, s.triggers.compiled, "c")
+	assert.Empty(t, s.triggers.last)
+}
+
+func TestServerCheckTriggersDoesNotBlock(t *testing.T) {
+	s, _ := newTriggerServer(t)
+	s.cfg.Triggers = []Trigger{{ID: "a", Pattern: "^go$", Action: TriggerActionCommand, Payload: "went"}}
+
+	// Lines beyond the size of the queue are dropped rather than blocking the console.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < triggerQueueSize*2; i++ {
+			s.checkTriggers("line")
+		}
+		s.checkTriggers("go")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("checking triggers blocked the console")
+	}
+	s.CtxCancel()
+}
+
+func TestServerCheckTriggers(t *testing.T) {
+	s, env := newTriggerServer(t)
+	defer s.CtxCancel()
+	s.cfg.Triggers = []Trigger{{ID: "a", Pattern: "^go$", Action: TriggerActionCommand, Payload: "went"}}
+
+	s.checkTriggers("go")
+	assert.Eventually(t, func() bool { return len(env.sent()) == 1 }, time.Second, time.Millisecond*10)
+	assert.Equal(t, []string{"went"}, env.sent())
+}
+
+func TestWebhookHostAllowed(t *testing.T) {
+	orig := config.Get()
+	defer config.Set(orig)
+	config.Update(func(c *config.Configuration) {
+		c.System.ConsoleTriggers.WebhookAllowlist = []string{"hooks.example.com"}
+	})
+
+	assert.True(t, webhookHostAllowed("localhost"))
+	assert.True(t, webhookHostAllowed("127.0.0.1"))
+	assert.True(t, webhookHostAllowed("::1"))
+	assert.True(t, webhookHostAllowed("HOOKS.example.com"))
+	assert.False(t, webhookHostAllowed("example.com"))
+	assert.False(t, webhookHostAllowed("10.0.0.1"))
+
+	s, _ := newTriggerServer(t)
+	err := s.sendTriggerWebhook("http://example.com/hook", TriggerEventData{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTriggerWebhookHost)
+}
@@ -17,7 +17,7 @@ import (
 // The server will be marked as requiring a rebuild on the next boot sequence,
 // it is up to the specific environment to determine what needs to happen when
 // that is the case.
-func (s *Server) UpdateDataStructure(data []byte) error {
+func (s *Server) UpdateDataStructure(data []byte) (err error) {
 	src := new(Configuration)
 	if err := json.Unmarshal(data, src); err != nil {
 		return errors.Wrap(err, "server/update: could not unmarshal source data into Configuration struct")
@@ -30,6 +30,27 @@ func (s *Server) UpdateDataStructure(data []byte) error {
 		return errors.New("server/update: attempting to merge a data stack with an invalid UUID")
 	}
 
+	// Drop any console triggers that cannot be tracked before the configuration is
+	// locked, since the warnings are logged through the server's locked accessors.
+	if src.Triggers != nil {
+		src.Triggers = s.validTriggers(src.Triggers)
+	}
+	if src.Egg.Triggers != nil {
+		src.Egg.Triggers = s.validTriggers(src.Egg.Triggers)
+	}
+
+	rollback, err := s.checkAllocations(src.Uuid, data)
+	if err != nil {
+		return err
+	}
+	// Release the ports again if the rest of the update fails so that they are
+	// not left reserved for a configuration that was never applied.
+	defer func() {
+		if err != nil {
+			rollback()
+		}
+	}()
+
 	// Grab a copy of the configuration to work on.
 	c := *s.Config()
 
@@ -40,6 +61,16 @@ func (s *Server) UpdateDataStructure(data []byte) error {
 	// server.
 	c.mu.Lock()
 
+	// Stop listening for connections on a hibernating server once hibernation has been
+	// disabled for it. This must happen after the configuration is unlocked since the
+	// hibernator reads the configuration while holding its own lock.
+	var hibernationDisabled bool
+	defer func() {
+		if err == nil && hibernationDisabled {
+			s.Hibernator().release()
+		}
+	}()
+
 	// Lock the server configuration while we're doing this merge to avoid anything
 	// trying to overwrite it or make modifications while we're sorting out what we
 	// need to do.
@@ -114,10 +145,54 @@ func (s *Server) UpdateDataStructure(data []byte) error {
 		c.Allocations.Mappings = src.Allocations.Mappings
 	}
 
+	// Ranges are replaced whenever they are sent so that all of them can be removed.
+	if _, _, _, err := jsonparser.Get(data, "allocations", "ranges"); err == nil {
+		c.Allocations.Ranges = src.Allocations.Ranges
+	}
+
 	if src.Mounts != nil && len(src.Mounts) > 0 {
 		c.Mounts = src.Mounts
 	}
 
+	// Schedules are always replaced entirely when they are present so that a schedule
+	// deleted on the Panel is also removed here. An empty array clears them all.
+	if src.Schedules != nil {
+		c.Schedules = src.Schedules
+	}
+
+	// The same applies to console triggers, both those on the server and the egg.
+	if src.Triggers != nil {
+		c.Triggers = src.Triggers
+	}
+	if src.Egg.Triggers != nil {
+		c.Egg.Triggers = src.Egg.Triggers
+	}
+
+	// Mergo would skip an empty protocol, so replace the query configuration whenever
+	// it is present to allow it to be removed.
+	if _, _, _, err := jsonparser.Get(data, "query"); err == nil {
+		c.Query = src.Query
+	}
+
+	// Container options are replaced entirely so that options can be removed.
+	if _, _, _, err := jsonparser.Get(data, "container_options"); err == nil {
+		c.ContainerOptions = src.ContainerOptions
+	}
+	if _, _, _, err := jsonparser.Get(data, "egg", "container_options"); err == nil {
+		c.Egg.ContainerOptions = src.Egg.ContainerOptions
+	}
+
+	// The network settings are also replaced entirely so firewall rules can be removed.
+	if _, _, _, err := jsonparser.Get(data, "network"); err == nil {
+		c.Network = src.Network
+	}
+
+	// The same applies to hibernation since it can be disabled.
+	if _, _, _, err := jsonparser.Get(data, "hibernation"); err == nil {
+		hibernationDisabled = c.Hibernation.Enabled && !src.Hibernation.Enabled
+		c.Hibernation = src.Hibernation
+	}
+
 	// Update the configuration once we have a lock on the configuration object.
 	s.cfg = c
 
@@ -139,6 +214,9 @@ func (s *Server) SyncWithEnvironment() {
 		Mounts:      s.Mounts(),
 		Allocations: s.Config().Allocations,
 		Limits:      s.Config().Build,
+		Network:     s.Config().Network,
+		Options:     s.ContainerOptions(),
+		User:        s.User(),
 	})
 
 	// If build limits are changed, environment variables also change. Plus, any modifications to
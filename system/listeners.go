@@ -0,0 +1,130 @@
+package system
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// Listener is a socket on the host that is listening for connections.
+type Listener struct {
+	Protocol string `json:"protocol"`
+	Ip       string `json:"ip"`
+	Port     int    `json:"port"`
+	// The process that owns the socket. These are only known if Wings is able to
+	// read the file descriptors of the process.
+	Pid     int    `json:"pid,omitempty"`
+	Command string `json:"command,omitempty"`
+
+	inode string
+}
+
+// The socket states that indicate something is listening, TCP_LISTEN for TCP
+// sockets and TCP_CLOSE for unconnected UDP sockets.
+var listenStates = map[string]string{
+	"tcp": "0A",
+	"udp": "07",
+}
+
+// Listeners returns all of the TCP and UDP sockets on the host that are listening
+// for connections. This reads from /proc and is only supported on Linux.
+func Listeners() ([]Listener, error) {
+	var out []Listener
+	for _, p := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		f, err := os.Open(filepath.Join("/proc/net", p))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrap(err, "system: could not read socket table")
+		}
+		l, err := parseProcNet(f, strings.TrimSuffix(p, "6"))
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l...)
+	}
+
+	owners := socketOwners("/proc")
+	for i, l := range out {
+		if pid, ok := owners[l.inode]; ok {
+			out[i].Pid = pid
+			if b, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm")); err == nil {
+				out[i].Command = strings.TrimSpace(string(b))
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseProcNet parses a socket table in the format used by /proc/net/tcp and
+// returns the sockets that are listening.
+func parseProcNet(r io.Reader, proto string) ([]Listener, error) {
+	var out []Listener
+	s := bufio.NewScanner(r)
+	// Skip over the header line.
+	s.Scan()
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) < 10 || f[3] != listenStates[proto] {
+			continue
+		}
+		i := strings.IndexByte(f[1], ':')
+		if i < 0 {
+			continue
+		}
+		ip, err := parseProcIp(f[1][:i])
+		if err != nil {
+			return nil, err
+		}
+		port, err := strconv.ParseUint(f[1][i+1:], 16, 16)
+		if err != nil {
+			return nil, errors.Wrap(err, "system: could not parse socket port")
+		}
+		out = append(out, Listener{Protocol: proto, Ip: ip.String(), Port: int(port), inode: f[9]})
+	}
+	return out, errors.WithStack(s.Err())
+}
+
+// parseProcIp parses an address from a socket table which is stored as a hex
+// encoded series of 32-bit words in host (little endian) byte order.
+func parseProcIp(v string) (net.IP, error) {
+	b, err := hex.DecodeString(v)
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, errors.New("system: could not parse socket address: " + v)
+	}
+	for i := 0; i < len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	return net.IP(b), nil
+}
+
+// socketOwners returns a map of socket inodes to the process that has them open.
+// Processes that cannot be inspected are skipped.
+func socketOwners(root string) map[string]int {
+	out := make(map[string]int)
+	procs, _ := ioutil.ReadDir(root)
+	for _, p := range procs {
+		pid, err := strconv.Atoi(p.Name())
+		if err != nil {
+			continue
+		}
+		fds, _ := ioutil.ReadDir(filepath.Join(root, p.Name(), "fd"))
+		for _, fd := range fds {
+			l, err := os.Readlink(filepath.Join(root, p.Name(), "fd", fd.Name()))
+			if err == nil && strings.HasPrefix(l, "socket:[") {
+				out[strings.TrimSuffix(strings.TrimPrefix(l, "socket:["), "]")] = pid
+			}
+		}
+	}
+	return out
+}
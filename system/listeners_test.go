@@ -0,0 +1,32 @@
+package system
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcNet(t *testing.T) {
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:63DD 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 662 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000     0        0 917 1 0000000000000000 20 4 30 10 -1
+`
+	l, err := parseProcNet(strings.NewReader(tcp), "tcp")
+	require.NoError(t, err)
+	require.Len(t, l, 1)
+	assert.Equal(t, "0.0.0.0", l[0].Ip)
+	assert.Equal(t, 25565, l[0].Port)
+	assert.Equal(t, "662", l[0].inode)
+
+	udp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  12: B80D0120000000000000000005000000:6987 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 1234 2 0000000000000000 0
+`
+	l, err = parseProcNet(strings.NewReader(udp6), "udp")
+	require.NoError(t, err)
+	require.Len(t, l, 1)
+	assert.Equal(t, "2001:db8::5", l[0].Ip)
+	assert.Equal(t, 27015, l[0].Port)
+	assert.Equal(t, "udp", l[0].Protocol)
+}